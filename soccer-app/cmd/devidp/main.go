@@ -0,0 +1,33 @@
+// Command devidp is a minimal stand-in OpenID Connect provider for local
+// development and manual testing of the OIDC login flow. It signs in any
+// subject you type into its form. Never expose it outside localhost.
+//
+//	go run ./cmd/devidp -addr :9000
+//	OIDC_ISSUER=http://localhost:9000 OIDC_CLIENT_ID=soccer-app \
+//	OIDC_CLIENT_SECRET=dev-secret \
+//	OIDC_REDIRECT_URL=http://localhost:8080/api/v1/auth/oidc/callback go run .
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"soccer-app/devidp"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL advertised in discovery and tokens")
+	clientID := flag.String("client-id", "soccer-app", "accepted client_id")
+	clientSecret := flag.String("client-secret", "dev-secret", "accepted client_secret")
+	flag.Parse()
+
+	p, err := devidp.New(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("dev OIDC provider on %s (issuer %s, client %s)", *addr, *issuer, *clientID)
+	log.Fatal(http.ListenAndServe(*addr, p.Handler()))
+}
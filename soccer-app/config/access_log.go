@@ -0,0 +1,39 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+// AccessLog controls how login events are queued and how long they are
+// kept.
+type AccessLog struct {
+	Retention time.Duration
+	QueueSize int // events waiting for geo enrichment before new ones are dropped
+	Workers   int
+}
+
+func (a *AccessLog) apply(v values) error {
+	days := int(a.Retention / (24 * time.Hour))
+	err := errors.Join(
+		v.int("LOGIN_EVENT_RETENTION_DAYS", &days),
+		v.int("LOGIN_EVENT_QUEUE_SIZE", &a.QueueSize),
+		v.int("LOGIN_EVENT_WORKERS", &a.Workers),
+	)
+	a.Retention = time.Duration(days) * 24 * time.Hour
+	return err
+}
+
+func (a AccessLog) validate() error {
+	var errs []error
+	if a.Retention < 24*time.Hour {
+		errs = append(errs, errors.New("LOGIN_EVENT_RETENTION_DAYS: must be at least 1"))
+	}
+	if a.QueueSize < 1 {
+		errs = append(errs, errors.New("LOGIN_EVENT_QUEUE_SIZE: must be at least 1"))
+	}
+	if a.Workers < 1 {
+		errs = append(errs, errors.New("LOGIN_EVENT_WORKERS: must be at least 1"))
+	}
+	return errors.Join(errs...)
+}
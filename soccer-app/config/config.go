@@ -0,0 +1,319 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is everything the server reads at startup.
+//
+// Every setting has a KEY like MONGO_URI. Values are layered, later ones
+// winning: built-in defaults, then a config file (KEY=value lines, named
+// by -config or CONFIG_FILE), then environment variables, then flags
+// (-mongo-uri, i.e. the key in lower case with dashes).
+type Config struct {
+	Server        Server
+	Mongo         Mongo
+	Log           Log
+	CORS          CORS
+	RateLimit     RateLimit
+	Geo           Geo
+	AccessLog     AccessLog
+	LoginAlerts   LoginAlerts
+	WebAuthn      WebAuthn
+	OIDC          OIDC
+	AdminUsername string // bootstrapped as admin on startup
+}
+
+// keys lists every setting with its flag help.
+var keys = map[string]string{
+	"LISTEN_ADDR":     "address the HTTP server listens on",
+	"TIMEZONE":        "IANA time zone polls are scheduled in",
+	"TRUSTED_PROXIES": "comma-separated proxy IPs/CIDRs whose X-Forwarded-For is trusted",
+	"ADMIN_USERNAME":  "user to bootstrap as admin",
+
+	"READ_HEADER_TIMEOUT": "how long a client may take to send request headers",
+	"SHUTDOWN_TIMEOUT":    "how long shutdown waits for in-flight work",
+	"METRICS_TOKEN":       "bearer token required to read /metrics",
+
+	"MONGO_URI":                      "MongoDB connection string",
+	"MONGO_DB":                       "MongoDB database name",
+	"MONGO_CONNECT_TIMEOUT":          "timeout for the initial MongoDB connection",
+	"MONGO_SERVER_SELECTION_TIMEOUT": "how long an operation waits for a usable MongoDB server",
+	"MONGO_TIMEOUT":                  "default timeout for each MongoDB operation (0 for none)",
+
+	"LOG_LEVEL": "debug, info, warn or error",
+
+	"CORS_ORIGINS":           "comma-separated allowed origins, or * for any (without credentials)",
+	"CORS_ALLOW_CREDENTIALS": "allow cookies and auth headers on cross-origin requests",
+
+	"RATE_LIMIT_REQUESTS":       "requests allowed per IP per window",
+	"RATE_LIMIT_WINDOW":         "rate limit window",
+	"RATE_LIMIT_LOGIN_REQUESTS": "sign-in attempts allowed per IP per window",
+	"RATE_LIMIT_LOGIN_WINDOW":   "sign-in rate limit window",
+	"RATE_LIMIT_VOTES_REQUESTS": "votes allowed per user per window",
+	"RATE_LIMIT_VOTES_WINDOW":   "vote rate limit window",
+	"RATE_LIMIT_STORE":          "memory, or mongo to share limits between instances",
+
+	"GEO_PROVIDER":   "ipinfo, mmdb or none (default: picked from the credentials set)",
+	"IPINFO_TOKEN":   "ipinfo.io API token",
+	"GEOIP_DB":       "path to a MaxMind-format city database",
+	"GEOIP_ASN_DB":   "path to a MaxMind-format ASN database",
+	"GEO_CACHE_SIZE": "geo lookups kept in memory",
+	"GEO_CACHE_TTL":  "how long a geo lookup is cached",
+
+	"LOGIN_EVENT_RETENTION_DAYS": "days login events are kept",
+	"LOGIN_EVENT_QUEUE_SIZE":     "login events buffered for enrichment before new ones are dropped",
+	"LOGIN_EVENT_WORKERS":        "login event enrichment workers",
+
+	"LOGIN_ALERT_MAX_KMH":  "speed between two sign-ins that counts as impossible travel",
+	"LOGIN_ALERT_REVERIFY": "require a reset code after impossible travel",
+	"LOGIN_ALERT_WEBHOOK":  "URL alerts are POSTed to",
+
+	"WEBAUTHN_RP_ID":      "passkey relying party ID",
+	"WEBAUTHN_RP_ORIGINS": "comma-separated passkey origins",
+
+	"OIDC_ISSUER":        "OIDC issuer URL; empty disables OIDC login",
+	"OIDC_CLIENT_ID":     "OIDC client ID",
+	"OIDC_CLIENT_SECRET": "OIDC client secret",
+	"OIDC_REDIRECT_URL":  "OIDC redirect URL",
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:              ":8080",
+			Timezone:          "America/Chicago",
+			ReadHeaderTimeout: 10 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+		},
+		Mongo: Mongo{
+			URI:                    "mongodb://localhost:27017",
+			Database:               "soccer_app",
+			ConnectTimeout:         10 * time.Second,
+			ServerSelectionTimeout: 10 * time.Second,
+		},
+		Log: Log{Level: slog.LevelInfo},
+		CORS: CORS{
+			Origins:          []string{"http://localhost:8080"},
+			AllowCredentials: true,
+		},
+		RateLimit: RateLimit{
+			Default: Limit{Requests: 100, Window: time.Minute},
+			Login:   Limit{Requests: 10, Window: time.Minute},
+			Votes:   Limit{Requests: 20, Window: time.Minute},
+			Store:   "memory",
+		},
+		Geo: Geo{
+			CacheSize: 10000,
+			CacheTTL:  24 * time.Hour,
+		},
+		AccessLog: AccessLog{
+			Retention: 90 * 24 * time.Hour,
+			QueueSize: 1024,
+			Workers:   4,
+		},
+		LoginAlerts: LoginAlerts{
+			MaxTravelKmh: 900, // roughly airliner speed
+		},
+		WebAuthn: WebAuthn{
+			RPID:          "localhost",
+			RPDisplayName: "Soccer App",
+			RPOrigins:     []string{"http://localhost:8080"},
+		},
+	}
+}
+
+// Load builds the configuration from args (normally os.Args[1:]), the
+// environment and an optional config file, and validates it.
+func Load(args []string) (Config, error) {
+	fs := flag.NewFlagSet("soccer-app", flag.ContinueOnError)
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "config file of KEY=value lines")
+	flags := make(map[string]*string, len(keys))
+	for key, help := range keys {
+		flags[key] = fs.String(flagName(key), "", help+" ("+key+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	v := values{}
+	if *file != "" {
+		if err := v.readFile(*file); err != nil {
+			return Config{}, err
+		}
+	}
+	for key := range keys {
+		if s, ok := os.LookupEnv(key); ok {
+			v[key] = s
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		for key, p := range flags {
+			if flagName(key) == f.Name {
+				v[key] = *p
+			}
+		}
+	})
+
+	cfg := Default()
+	if err := cfg.apply(v); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) apply(v values) error {
+	v.str("ADMIN_USERNAME", &cfg.AdminUsername)
+	return errors.Join(
+		cfg.Server.apply(v),
+		cfg.Mongo.apply(v),
+		cfg.Log.apply(v),
+		cfg.CORS.apply(v),
+		cfg.RateLimit.apply(v),
+		cfg.Geo.apply(v),
+		cfg.AccessLog.apply(v),
+		cfg.LoginAlerts.apply(v),
+		cfg.WebAuthn.apply(v),
+		cfg.OIDC.apply(v),
+	)
+}
+
+// Validate reports every setting that is out of range or inconsistent.
+func (cfg Config) Validate() error {
+	return errors.Join(
+		cfg.Server.validate(),
+		cfg.Mongo.validate(),
+		cfg.CORS.validate(),
+		cfg.RateLimit.validate(),
+		cfg.Geo.validate(),
+		cfg.AccessLog.validate(),
+		cfg.LoginAlerts.validate(),
+		cfg.OIDC.validate(),
+	)
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+// values holds the raw settings by key once all sources are merged.
+type values map[string]string
+
+// readFile loads KEY=value lines. Blank lines and lines starting with #
+// are skipped; values may be quoted.
+func (v values) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	defer f.Close()
+	return v.read(f, path)
+}
+
+func (v values) read(r io.Reader, name string) error {
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok {
+			return fmt.Errorf("%s:%d: expected KEY=value", name, n)
+		}
+		if _, known := keys[key]; !known {
+			return fmt.Errorf("%s:%d: unknown setting %s", name, n, key)
+		}
+		val = strings.TrimSpace(val)
+		if uq, err := strconv.Unquote(val); err == nil {
+			val = uq
+		}
+		v[key] = val
+	}
+	return sc.Err()
+}
+
+func (v values) str(key string, dst *string) {
+	if s, ok := v[key]; ok {
+		*dst = s
+	}
+}
+
+func (v values) list(key string, dst *[]string) {
+	s, ok := v[key]
+	if !ok {
+		return
+	}
+	*dst = nil
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*dst = append(*dst, item)
+		}
+	}
+}
+
+func (v values) int(key string, dst *int) error {
+	s, ok := v[key]
+	if !ok || s == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a whole number", key, s)
+	}
+	*dst = n
+	return nil
+}
+
+func (v values) float(key string, dst *float64) error {
+	s, ok := v[key]
+	if !ok || s == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a number", key, s)
+	}
+	*dst = f
+	return nil
+}
+
+func (v values) bool(key string, dst *bool) error {
+	s, ok := v[key]
+	if !ok || s == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not true or false", key, s)
+	}
+	*dst = b
+	return nil
+}
+
+func (v values) duration(key string, dst *time.Duration) error {
+	s, ok := v[key]
+	if !ok || s == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a duration like 30s or 5m", key, s)
+	}
+	*dst = d
+	return nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "soccer.conf")
+	if err := os.WriteFile(file, []byte("# local overrides\nMONGO_DB=from_file\nMONGO_TIMEOUT=\"5s\"\nLOG_LEVEL=debug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{"default", nil, nil, "soccer_app"},
+		{"file", map[string]string{"CONFIG_FILE": file}, nil, "from_file"},
+		{"env over file", map[string]string{"CONFIG_FILE": file, "MONGO_DB": "from_env"}, nil, "from_env"},
+		{"flag over env", map[string]string{"CONFIG_FILE": file, "MONGO_DB": "from_env"}, []string{"-mongo-db", "from_flag"}, "from_flag"},
+		{"-config flag", nil, []string{"-config", file}, "from_file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", "")
+			t.Setenv("MONGO_DB", "")
+			os.Unsetenv("MONGO_DB")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := Load(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Mongo.Database != tt.want {
+				t.Fatalf("MONGO_DB = %q, want %q", cfg.Mongo.Database, tt.want)
+			}
+		})
+	}
+
+	// Settings a layer leaves alone keep the value from the one below
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("MONGO_DB", "from_env")
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Mongo.Timeout != 5*time.Second || cfg.Mongo.URI != "mongodb://localhost:27017" {
+		t.Fatalf("timeout %v, uri %q: want the file's timeout and the default URI", cfg.Mongo.Timeout, cfg.Mongo.URI)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		set  values
+		want []string // substrings of the error; none means valid
+	}{
+		{"defaults", values{}, nil},
+		{"listen addr", values{"LISTEN_ADDR": "8080"}, []string{"LISTEN_ADDR"}},
+		{"time zone", values{"TIMEZONE": "Mars/Olympus"}, []string{"TIMEZONE"}},
+		{"mongo uri", values{"MONGO_URI": "localhost:27017"}, []string{"MONGO_URI"}},
+		{"every error at once", values{"MONGO_DB": "", "RATE_LIMIT_REQUESTS": "0", "RATE_LIMIT_STORE": "redis"},
+			[]string{"MONGO_DB", "RATE_LIMIT_REQUESTS", "RATE_LIMIT_STORE"}},
+		{"not a number", values{"RATE_LIMIT_REQUESTS": "lots"}, []string{"not a whole number"}},
+		{"not a duration", values{"RATE_LIMIT_WINDOW": "1 minute"}, []string{"not a duration"}},
+		{"log level", values{"LOG_LEVEL": "loud"}, []string{"LOG_LEVEL"}},
+		{"geo provider without token", values{"GEO_PROVIDER": "ipinfo"}, []string{"IPINFO_TOKEN"}},
+		{"geo provider from credentials", values{"IPINFO_TOKEN": "tok"}, nil},
+		{"cors origin", values{"CORS_ORIGINS": "example.com"}, []string{"not an origin"}},
+		{"cors any origin with credentials", values{"CORS_ORIGINS": "*"}, []string{"CORS_ALLOW_CREDENTIALS"}},
+		{"cors any origin without credentials", values{"CORS_ORIGINS": "*", "CORS_ALLOW_CREDENTIALS": "false"}, nil},
+		{"cors listed origins with credentials", values{"CORS_ORIGINS": "https://a.example, https://b.example"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			err := cfg.apply(tt.set)
+			if err == nil {
+				err = cfg.Validate()
+			}
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("no error, want one mentioning %v", tt.want)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error %q does not mention %s", err, w)
+				}
+			}
+		})
+	}
+}
+
+func TestReadFileErrors(t *testing.T) {
+	tests := []struct {
+		name, body, want string
+	}{
+		{"no equals", "MONGO_DB\n", "soccer.conf:1: expected KEY=value"},
+		{"unknown key", "\n# comment\nMONGO_DATABASE=x\n", "soccer.conf:3: unknown setting MONGO_DATABASE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := values{}.read(strings.NewReader(tt.body), "soccer.conf")
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// CORS lists the browser origins allowed to call the API. A lone "*"
+// allows any origin, but only without credentials: a page on any site
+// could otherwise make signed-in requests on a visitor's behalf.
+type CORS struct {
+	Origins          []string
+	AllowCredentials bool
+}
+
+func (c *CORS) apply(v values) error {
+	v.list("CORS_ORIGINS", &c.Origins)
+	return v.bool("CORS_ALLOW_CREDENTIALS", &c.AllowCredentials)
+}
+
+func (c CORS) AllowAll() bool {
+	return len(c.Origins) == 1 && c.Origins[0] == "*"
+}
+
+func (c CORS) validate() error {
+	if len(c.Origins) == 0 {
+		return errors.New("CORS_ORIGINS: must not be empty")
+	}
+	if c.AllowAll() {
+		if c.AllowCredentials {
+			return errors.New("CORS_ORIGINS: * can't be used with CORS_ALLOW_CREDENTIALS; list the origins")
+		}
+		return nil
+	}
+	var errs []error
+	for _, o := range c.Origins {
+		u, err := url.Parse(o)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("CORS_ORIGINS: %q is not an origin like https://example.com", o))
+		}
+	}
+	return errors.Join(errs...)
+}
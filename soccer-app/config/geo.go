@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Geo selects how client IPs are geolocated. Provider is "ipinfo", "mmdb"
+// or "none"; when unset it is picked from whichever credentials are set.
+type Geo struct {
+	Provider    string
+	IPInfoToken string
+	MMDBPath    string // MaxMind-format city database
+	ASNPath     string // optional MaxMind-format ASN database, for ISP
+	CacheSize   int    // 0 disables the cache
+	CacheTTL    time.Duration
+}
+
+func (g *Geo) apply(v values) error {
+	v.str("GEO_PROVIDER", &g.Provider)
+	v.str("IPINFO_TOKEN", &g.IPInfoToken)
+	v.str("GEOIP_DB", &g.MMDBPath)
+	v.str("GEOIP_ASN_DB", &g.ASNPath)
+	err := errors.Join(
+		v.int("GEO_CACHE_SIZE", &g.CacheSize),
+		v.duration("GEO_CACHE_TTL", &g.CacheTTL),
+	)
+	if g.Provider == "" {
+		switch {
+		case g.MMDBPath != "":
+			g.Provider = "mmdb"
+		case g.IPInfoToken != "":
+			g.Provider = "ipinfo"
+		default:
+			g.Provider = "none"
+		}
+	}
+	return err
+}
+
+func (g Geo) validate() error {
+	var errs []error
+	switch g.Provider {
+	case "none":
+	case "ipinfo":
+		if g.IPInfoToken == "" {
+			errs = append(errs, errors.New("IPINFO_TOKEN: required by the ipinfo provider"))
+		}
+	case "mmdb":
+		if g.MMDBPath == "" {
+			errs = append(errs, errors.New("GEOIP_DB: required by the mmdb provider"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("GEO_PROVIDER: %q is not ipinfo, mmdb or none", g.Provider))
+	}
+	if g.CacheSize < 0 {
+		errs = append(errs, errors.New("GEO_CACHE_SIZE: must not be negative"))
+	}
+	if g.CacheSize > 0 && g.CacheTTL <= 0 {
+		errs = append(errs, errors.New("GEO_CACHE_TTL: must be positive"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,22 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Log controls the server's structured logger.
+type Log struct {
+	Level slog.Level
+}
+
+func (l *Log) apply(v values) error {
+	s, ok := v["LOG_LEVEL"]
+	if !ok || s == "" {
+		return nil
+	}
+	if err := l.Level.UnmarshalText([]byte(s)); err != nil {
+		return fmt.Errorf("LOG_LEVEL: %q is not debug, info, warn or error", s)
+	}
+	return nil
+}
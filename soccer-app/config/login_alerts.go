@@ -0,0 +1,39 @@
+package config
+
+import (
+	"errors"
+	"net/url"
+)
+
+// LoginAlerts controls suspicious-login detection. Alerts are always
+// stored; the other settings decide what else happens.
+type LoginAlerts struct {
+	// Faster than this between two logins counts as impossible travel
+	MaxTravelKmh float64
+	// Impossible travel locks password login until a reset code is used
+	RequireReverification bool
+	// Alerts are POSTed here as JSON, e.g. to a chat webhook the
+	// organisers watch
+	WebhookURL string
+}
+
+func (l *LoginAlerts) apply(v values) error {
+	v.str("LOGIN_ALERT_WEBHOOK", &l.WebhookURL)
+	return errors.Join(
+		v.float("LOGIN_ALERT_MAX_KMH", &l.MaxTravelKmh),
+		v.bool("LOGIN_ALERT_REVERIFY", &l.RequireReverification),
+	)
+}
+
+func (l LoginAlerts) validate() error {
+	var errs []error
+	if l.MaxTravelKmh <= 0 {
+		errs = append(errs, errors.New("LOGIN_ALERT_MAX_KMH: must be positive"))
+	}
+	if l.WebhookURL != "" {
+		if u, err := url.Parse(l.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, errors.New("LOGIN_ALERT_WEBHOOK: must be an http(s) URL"))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -2,21 +2,77 @@ package config
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func MustMongo() *mongo.Database {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Mongo holds the database connection settings.
+type Mongo struct {
+	URI                    string
+	Database               string
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+	Timeout                time.Duration // per operation; 0 leaves it to the caller's context
+}
+
+func (m *Mongo) apply(v values) error {
+	v.str("MONGO_URI", &m.URI)
+	v.str("MONGO_DB", &m.Database)
+	return errors.Join(
+		v.duration("MONGO_CONNECT_TIMEOUT", &m.ConnectTimeout),
+		v.duration("MONGO_SERVER_SELECTION_TIMEOUT", &m.ServerSelectionTimeout),
+		v.duration("MONGO_TIMEOUT", &m.Timeout),
+	)
+}
+
+func (m Mongo) validate() error {
+	var errs []error
+	if !strings.HasPrefix(m.URI, "mongodb://") && !strings.HasPrefix(m.URI, "mongodb+srv://") {
+		errs = append(errs, errors.New("MONGO_URI: must start with mongodb:// or mongodb+srv://"))
+	}
+	if m.Database == "" {
+		errs = append(errs, errors.New("MONGO_DB: must not be empty"))
+	}
+	if m.ConnectTimeout <= 0 {
+		errs = append(errs, errors.New("MONGO_CONNECT_TIMEOUT: must be positive"))
+	}
+	if m.ServerSelectionTimeout <= 0 {
+		errs = append(errs, errors.New("MONGO_SERVER_SELECTION_TIMEOUT: must be positive"))
+	}
+	if m.Timeout < 0 {
+		errs = append(errs, errors.New("MONGO_TIMEOUT: must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// ConnectMongo connects and pings the server, so a bad URI or an
+// unreachable database fails at startup rather than on the first request.
+// extra options, such as a command monitor, are applied on top of cfg.
+func ConnectMongo(ctx context.Context, cfg Mongo, extra ...*options.ClientOptions) (*mongo.Database, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017"))
+	opts := options.Client().
+		ApplyURI(cfg.URI).
+		SetConnectTimeout(cfg.ConnectTimeout).
+		SetServerSelectionTimeout(cfg.ServerSelectionTimeout)
+	if cfg.Timeout > 0 {
+		opts.SetTimeout(cfg.Timeout)
+	}
+
+	client, err := mongo.Connect(ctx, append([]*options.ClientOptions{opts}, extra...)...)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("mongo connect: %w", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("mongo ping: %w", err)
 	}
 
-	return client.Database("soccer_app")
-}
\ No newline at end of file
+	return client.Database(cfg.Database), nil
+}
@@ -0,0 +1,31 @@
+package config
+
+import "errors"
+
+// OIDC holds the settings for signing in with an external identity
+// provider. Login via OIDC is disabled when Issuer is empty.
+type OIDC struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+func (o *OIDC) apply(v values) error {
+	v.str("OIDC_ISSUER", &o.Issuer)
+	v.str("OIDC_CLIENT_ID", &o.ClientID)
+	v.str("OIDC_CLIENT_SECRET", &o.ClientSecret)
+	v.str("OIDC_REDIRECT_URL", &o.RedirectURL)
+	return nil
+}
+
+func (o OIDC) Enabled() bool {
+	return o.Issuer != "" && o.ClientID != ""
+}
+
+func (o OIDC) validate() error {
+	if o.Issuer != "" && (o.ClientID == "" || o.RedirectURL == "") {
+		return errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL: required when OIDC_ISSUER is set")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Limit allows Requests per Window, refilled smoothly, with bursts of up
+// to Requests.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimit holds the per-client request limits. Default applies to every
+// request per IP; Login to sign-in attempts per IP; Votes to votes per
+// user, or per IP when anonymous. Store is "memory" for a single
+// instance or "mongo" to share counts between instances.
+type RateLimit struct {
+	Default Limit
+	Login   Limit
+	Votes   Limit
+	Store   string
+}
+
+func (r *RateLimit) apply(v values) error {
+	v.str("RATE_LIMIT_STORE", &r.Store)
+	return errors.Join(
+		r.Default.apply(v, "RATE_LIMIT"),
+		r.Login.apply(v, "RATE_LIMIT_LOGIN"),
+		r.Votes.apply(v, "RATE_LIMIT_VOTES"),
+	)
+}
+
+func (r RateLimit) validate() error {
+	errs := []error{
+		r.Default.validate("RATE_LIMIT"),
+		r.Login.validate("RATE_LIMIT_LOGIN"),
+		r.Votes.validate("RATE_LIMIT_VOTES"),
+	}
+	if r.Store != "memory" && r.Store != "mongo" {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_STORE: %q is not memory or mongo", r.Store))
+	}
+	return errors.Join(errs...)
+}
+
+func (l *Limit) apply(v values, prefix string) error {
+	return errors.Join(
+		v.int(prefix+"_REQUESTS", &l.Requests),
+		v.duration(prefix+"_WINDOW", &l.Window),
+	)
+}
+
+func (l Limit) validate(prefix string) error {
+	var errs []error
+	if l.Requests < 1 {
+		errs = append(errs, errors.New(prefix+"_REQUESTS: must be at least 1"))
+	}
+	if l.Window <= 0 {
+		errs = append(errs, errors.New(prefix+"_WINDOW: must be positive"))
+	}
+	return errors.Join(errs...)
+}
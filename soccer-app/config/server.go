@@ -0,0 +1,66 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Server holds the HTTP listener settings.
+type Server struct {
+	Addr              string
+	Timezone          string   // IANA name, e.g. America/Chicago
+	TrustedProxies    []string // none by default: ClientIP is the socket address
+	ReadHeaderTimeout time.Duration
+	// How long shutdown waits for in-flight requests and queued login
+	// events before giving up on them
+	ShutdownTimeout time.Duration
+	// Bearer token scrapers must send to /metrics; empty leaves it open
+	MetricsToken string
+}
+
+func (s *Server) apply(v values) error {
+	v.str("LISTEN_ADDR", &s.Addr)
+	v.str("TIMEZONE", &s.Timezone)
+	v.list("TRUSTED_PROXIES", &s.TrustedProxies)
+	v.str("METRICS_TOKEN", &s.MetricsToken)
+	return errors.Join(
+		v.duration("READ_HEADER_TIMEOUT", &s.ReadHeaderTimeout),
+		v.duration("SHUTDOWN_TIMEOUT", &s.ShutdownTimeout),
+	)
+}
+
+func (s Server) validate() error {
+	var errs []error
+	if _, _, err := net.SplitHostPort(s.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("LISTEN_ADDR: %q is not host:port", s.Addr))
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		errs = append(errs, fmt.Errorf("TIMEZONE: unknown time zone %q", s.Timezone))
+	}
+	if s.ReadHeaderTimeout <= 0 {
+		errs = append(errs, errors.New("READ_HEADER_TIMEOUT: must be positive"))
+	}
+	if s.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("SHUTDOWN_TIMEOUT: must be positive"))
+	}
+	for _, p := range s.TrustedProxies {
+		if net.ParseIP(p) == nil {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				errs = append(errs, fmt.Errorf("TRUSTED_PROXIES: %q is not an IP or CIDR", p))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Location is the time zone polls are scheduled in. Validate has already
+// checked that it loads.
+func (s Server) Location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,14 @@
+package config
+
+// WebAuthn holds the relying party settings for passkey login.
+type WebAuthn struct {
+	RPID          string
+	RPDisplayName string
+	RPOrigins     []string
+}
+
+func (w *WebAuthn) apply(v values) error {
+	v.str("WEBAUTHN_RP_ID", &w.RPID)
+	v.list("WEBAUTHN_RP_ORIGINS", &w.RPOrigins)
+	return nil
+}
@@ -0,0 +1,276 @@
+// Package devidp is a minimal OpenID Connect provider: discovery, JWKS, an
+// authorize form that signs in any subject typed into it, and a token
+// endpoint that checks PKCE and returns a signed id_token. It backs
+// cmd/devidp for local development and the OIDC handler tests. Never
+// expose it outside localhost.
+package devidp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+const keyID = "dev"
+
+// Grant is what the provider remembers about an authorization code it
+// handed out.
+type Grant struct {
+	ClientID      string
+	RedirectURI   string
+	Nonce         string
+	Subject       string
+	Email         string
+	CodeChallenge string
+	// Issuer overrides the provider's issuer in the id_token, so tests
+	// can hand out tokens the client must refuse
+	Issuer string
+}
+
+// Provider serves the OIDC endpoints for one client.
+type Provider struct {
+	// Issuer is advertised in discovery and tokens. Set it before serving
+	// when the address is only known once listening, as with httptest.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+
+	key    *rsa.PrivateKey
+	signer jose.Signer
+
+	mu     sync.Mutex
+	grants map[string]grantEntry
+}
+
+type grantEntry struct {
+	Grant
+	expiresAt time.Time
+}
+
+// New returns a provider with a fresh signing key.
+func New(issuer, clientID, clientSecret string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", keyID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		Issuer:       issuer,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		signer:       signer,
+		grants:       make(map[string]grantEntry),
+	}, nil
+}
+
+// Handler routes the provider's endpoints.
+func (p *Provider) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	return mux
+}
+
+// Issue stores g and returns a single-use code for it, valid for a
+// minute. The authorize form calls it; tests call it directly to skip the
+// browser step.
+func (p *Provider) Issue(g Grant) string {
+	code := randomString()
+	p.mu.Lock()
+	p.grants[code] = grantEntry{Grant: g, expiresAt: time.Now().Add(time.Minute)}
+	p.mu.Unlock()
+	return code
+}
+
+var authorizeForm = template.Must(template.New("authorize").Parse(`<!doctype html>
+<html><body style="font-family:sans-serif;max-width:420px;margin:40px auto">
+<h2>Dev identity provider</h2>
+<form method="post">
+  {{range $k, $v := .}}<input type="hidden" name="{{$k}}" value="{{index $v 0}}">{{end}}
+  <p><label>Subject<br><input name="sub" required value="dev-user-1"></label></p>
+  <p><label>Email<br><input name="email" value="dev@example.com"></label></p>
+  <button type="submit">Sign in</button>
+</form>
+</body></html>`))
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.Issuer,
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"token_endpoint":                        p.Issuer + "/token",
+		"jwks_uri":                              p.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key:       &p.key.PublicKey,
+		KeyID:     keyID,
+		Algorithm: string(jose.RS256),
+		Use:       "sig",
+	}}})
+}
+
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if r.Form.Get("client_id") != p.ClientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if r.Form.Get("response_type") != "code" {
+		http.Error(w, "only response_type=code is supported", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = authorizeForm.Execute(w, r.URL.Query())
+		return
+	}
+
+	redirect, err := url.Parse(r.Form.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := p.Issue(Grant{
+		ClientID:      r.Form.Get("client_id"),
+		RedirectURI:   r.Form.Get("redirect_uri"),
+		Nonce:         r.Form.Get("nonce"),
+		Subject:       r.Form.Get("sub"),
+		Email:         r.Form.Get("email"),
+		CodeChallenge: r.Form.Get("code_challenge"),
+	})
+
+	q := redirect.Query()
+	q.Set("code", code)
+	q.Set("state", r.Form.Get("state"))
+	redirect.RawQuery = q.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.Form.Get("client_id"), r.Form.Get("client_secret")
+	}
+	if clientID != p.ClientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(p.ClientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	if r.Form.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	p.mu.Lock()
+	g, found := p.grants[r.Form.Get("code")]
+	delete(p.grants, r.Form.Get("code"))
+	p.mu.Unlock()
+
+	if !found || time.Now().After(g.expiresAt) ||
+		g.ClientID != clientID || g.RedirectURI != r.Form.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	// PKCE (S256)
+	if g.CodeChallenge != "" {
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != g.CodeChallenge {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+	}
+
+	issuer := g.Issuer
+	if issuer == "" {
+		issuer = p.Issuer
+	}
+
+	now := time.Now()
+	claims := struct {
+		jwt.Claims
+		Nonce         string `json:"nonce,omitempty"`
+		Email         string `json:"email,omitempty"`
+		EmailVerified bool   `json:"email_verified,omitempty"`
+	}{
+		Claims: jwt.Claims{
+			Issuer:   issuer,
+			Subject:  g.Subject,
+			Audience: jwt.Audience{clientID},
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry:   jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+		Nonce:         g.Nonce,
+		Email:         g.Email,
+		EmailVerified: g.Email != "",
+	}
+
+	idToken, err := jwt.Signed(p.signer).Claims(claims).Serialize()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
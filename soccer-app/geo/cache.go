@@ -0,0 +1,97 @@
+package geo
+
+import (
+	"container/list"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Cache keeps the most recently used lookups of another provider for up
+// to ttl. Failed lookups are not cached.
+type Cache struct {
+	next Provider
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu    sync.Mutex
+	order *list.List // front is most recently used
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	ip      string
+	info    Info
+	expires time.Time
+}
+
+func NewCache(next Provider, size int, ttl time.Duration) *Cache {
+	return &Cache{
+		next:  next,
+		size:  size,
+		ttl:   ttl,
+		now:   time.Now,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+func (c *Cache) Lookup(ctx context.Context, ip string) (Info, error) {
+	if info, ok := c.get(ip); ok {
+		return info, nil
+	}
+
+	info, err := c.next.Lookup(ctx, ip)
+	if err != nil {
+		return Info{}, err
+	}
+	c.put(ip, info)
+	return info, nil
+}
+
+// Close closes the wrapped provider if it holds resources.
+func (c *Cache) Close() error {
+	if cl, ok := c.next.(io.Closer); ok {
+		return cl.Close()
+	}
+	return nil
+}
+
+func (c *Cache) get(ip string) (Info, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[ip]
+	if !ok {
+		return Info{}, false
+	}
+	e := el.Value.(*cacheEntry)
+	if c.ttl > 0 && c.now().After(e.expires) {
+		c.order.Remove(el)
+		delete(c.items, ip)
+		return Info{}, false
+	}
+	c.order.MoveToFront(el)
+	return e.info, true
+}
+
+func (c *Cache) put(ip string, info Info) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.items[ip]; ok {
+		el.Value = &cacheEntry{ip: ip, info: info, expires: expires}
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[ip] = c.order.PushFront(&cacheEntry{ip: ip, info: info, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).ip)
+	}
+}
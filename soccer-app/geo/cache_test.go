@@ -0,0 +1,101 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// counter answers every lookup with the IP as the city, counting calls.
+type counter struct {
+	calls map[string]int
+	fail  bool
+}
+
+func (p *counter) Lookup(_ context.Context, ip string) (Info, error) {
+	p.calls[ip]++
+	if p.fail {
+		return Info{}, errors.New("provider down")
+	}
+	return Info{City: ip}, nil
+}
+
+func TestCache(t *testing.T) {
+	type step struct {
+		ip      string
+		advance time.Duration // clock moves before the lookup
+		fetched bool          // went to the provider
+	}
+	tests := []struct {
+		name  string
+		size  int
+		ttl   time.Duration
+		steps []step
+	}{
+		{"hit", 2, time.Hour, []step{
+			{"a", 0, true},
+			{"a", 0, false},
+		}},
+		{"least recently used goes first", 2, time.Hour, []step{
+			{"a", 0, true},
+			{"b", 0, true},
+			{"a", 0, false}, // b is now the oldest
+			{"c", 0, true},  // evicts b
+			{"a", 0, false},
+			{"b", 0, true},
+		}},
+		{"expires after ttl", 2, time.Minute, []step{
+			{"a", 0, true},
+			{"a", time.Minute, false},
+			{"a", time.Second, true},
+			{"a", 0, false},
+		}},
+		{"hits don't extend the ttl", 2, time.Minute, []step{
+			{"a", 0, true},
+			{"a", 50 * time.Second, false},
+			{"a", 50 * time.Second, true},
+		}},
+		{"no ttl", 2, 0, []step{
+			{"a", 0, true},
+			{"a", 24 * time.Hour, false},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &counter{calls: map[string]int{}}
+			c := NewCache(p, tt.size, tt.ttl)
+			clock := time.Unix(1_700_000_000, 0)
+			c.now = func() time.Time { return clock }
+
+			for i, st := range tt.steps {
+				clock = clock.Add(st.advance)
+				before := p.calls[st.ip]
+				info, err := c.Lookup(context.Background(), st.ip)
+				if err != nil || info.City != st.ip {
+					t.Fatalf("step %d: Lookup(%s) = %+v, %v", i+1, st.ip, info, err)
+				}
+				if fetched := p.calls[st.ip] > before; fetched != st.fetched {
+					t.Fatalf("step %d: Lookup(%s) fetched = %v, want %v", i+1, st.ip, fetched, st.fetched)
+				}
+			}
+			if len(c.items) > tt.size || c.order.Len() != len(c.items) {
+				t.Fatalf("%d items, %d in order; size %d", len(c.items), c.order.Len(), tt.size)
+			}
+		})
+	}
+}
+
+func TestCacheSkipsFailures(t *testing.T) {
+	p := &counter{calls: map[string]int{}, fail: true}
+	c := NewCache(p, 2, time.Hour)
+
+	for range 2 {
+		if _, err := c.Lookup(context.Background(), "a"); err == nil {
+			t.Fatal("error was swallowed")
+		}
+	}
+	if p.calls["a"] != 2 {
+		t.Fatalf("provider called %d times, want 2: failures must not be cached", p.calls["a"])
+	}
+}
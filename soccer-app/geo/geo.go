@@ -0,0 +1,92 @@
+// Package geo resolves IP addresses to an approximate location.
+package geo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+
+	"soccer-app/config"
+)
+
+// Info is what a provider knows about an IP. Lat and Lng are kept as
+// strings, as ipinfo returns them.
+type Info struct {
+	Country string
+	Region  string
+	City    string
+	ISP     string
+	Lat     string
+	Lng     string
+}
+
+// Provider looks up an IP address.
+type Provider interface {
+	Lookup(ctx context.Context, ip string) (Info, error)
+}
+
+// Noop knows nothing about any IP. It is used when geolocation is
+// disabled and in tests.
+type Noop struct{}
+
+func (Noop) Lookup(context.Context, string) (Info, error) {
+	return Info{}, nil
+}
+
+// IsPublic reports whether ip is worth looking up: private, loopback and
+// unparseable addresses never are.
+func IsPublic(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	return !parsed.IsPrivate() && !parsed.IsLoopback() && !parsed.IsUnspecified() && !parsed.IsLinkLocalUnicast()
+}
+
+// New builds the provider named in cfg, wrapped in a cache unless the
+// cache is disabled.
+func New(cfg config.Geo) (Provider, error) {
+	var p Provider
+	switch cfg.Provider {
+	case "ipinfo":
+		if cfg.IPInfoToken == "" {
+			return nil, fmt.Errorf("geo: ipinfo provider needs IPINFO_TOKEN")
+		}
+		p = NewIPInfo(cfg.IPInfoToken)
+	case "mmdb":
+		m, err := OpenMMDB(cfg.MMDBPath, cfg.ASNPath)
+		if err != nil {
+			return nil, err
+		}
+		p = m
+	case "", "none":
+		return Noop{}, nil
+	default:
+		return nil, fmt.Errorf("geo: unknown provider %q", cfg.Provider)
+	}
+
+	if cfg.CacheSize <= 0 {
+		return p, nil
+	}
+	return NewCache(p, cfg.CacheSize, cfg.CacheTTL), nil
+}
+
+// DistanceKm is the great-circle distance between two points given as the
+// Lat/Lng strings of Info. ok is false if either point is missing.
+func DistanceKm(lat1, lng1, lat2, lng2 string) (km float64, ok bool) {
+	var p [4]float64
+	for i, s := range []string{lat1, lng1, lat2, lng2} {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, false
+		}
+		p[i] = v * math.Pi / 180
+	}
+
+	const earthRadiusKm = 6371
+	dLat, dLng := p[2]-p[0], p[3]-p[1]
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(p[0])*math.Cos(p[2])*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a)), true
+}
@@ -0,0 +1,69 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// IPInfo looks IPs up with the ipinfo.io API.
+type IPInfo struct {
+	token  string
+	client *http.Client
+}
+
+func NewIPInfo(token string) *IPInfo {
+	return &IPInfo{
+		token:  token,
+		client: &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+type ipinfoResponse struct {
+	IP       string `json:"ip"`
+	City     string `json:"city"`
+	Region   string `json:"region"`
+	Country  string `json:"country"`
+	Org      string `json:"org"`
+	Loc      string `json:"loc"` // "lat,lng"
+	Timezone string `json:"timezone"`
+}
+
+func (p *IPInfo) Lookup(ctx context.Context, ip string) (Info, error) {
+	u := url.URL{Scheme: "https", Host: "ipinfo.io", Path: "/" + ip}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Info{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Info{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Info{}, fmt.Errorf("geo lookup failed: %s", resp.Status)
+	}
+
+	var r ipinfoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return Info{}, err
+	}
+
+	lat, lng, _ := strings.Cut(r.Loc, ",")
+	return Info{
+		Country: r.Country,
+		Region:  r.Region,
+		City:    r.City,
+		ISP:     r.Org,
+		Lat:     lat,
+		Lng:     lng,
+	}, nil
+}
@@ -0,0 +1,100 @@
+package geo
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// MMDB looks IPs up in local MaxMind-format databases, such as GeoLite2
+// City and, optionally, GeoLite2 ASN for the ISP.
+type MMDB struct {
+	city *maxminddb.Reader
+	asn  *maxminddb.Reader
+}
+
+// OpenMMDB opens the city database at cityPath and, if asnPath is set,
+// the ASN database.
+func OpenMMDB(cityPath, asnPath string) (*MMDB, error) {
+	if cityPath == "" {
+		return nil, fmt.Errorf("geo: mmdb provider needs GEOIP_DB")
+	}
+	city, err := maxminddb.Open(cityPath)
+	if err != nil {
+		return nil, fmt.Errorf("geo: open %s: %w", cityPath, err)
+	}
+
+	m := &MMDB{city: city}
+	if asnPath != "" {
+		if m.asn, err = maxminddb.Open(asnPath); err != nil {
+			city.Close()
+			return nil, fmt.Errorf("geo: open %s: %w", asnPath, err)
+		}
+	}
+	return m, nil
+}
+
+type mmdbCity struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+type mmdbASN struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+func (m *MMDB) Lookup(_ context.Context, ip string) (Info, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return Info{}, fmt.Errorf("geo: invalid IP %q", ip)
+	}
+
+	var rec mmdbCity
+	if err := m.city.Lookup(parsed, &rec); err != nil {
+		return Info{}, err
+	}
+
+	info := Info{
+		Country: rec.Country.ISOCode,
+		City:    rec.City.Names["en"],
+	}
+	if len(rec.Subdivisions) > 0 {
+		info.Region = rec.Subdivisions[0].Names["en"]
+	}
+	if rec.Location.Latitude != nil && rec.Location.Longitude != nil {
+		info.Lat = strconv.FormatFloat(*rec.Location.Latitude, 'f', 4, 64)
+		info.Lng = strconv.FormatFloat(*rec.Location.Longitude, 'f', 4, 64)
+	}
+
+	// Match ipinfo's "AS123 Org" format for the ISP
+	if m.asn != nil {
+		var as mmdbASN
+		if err := m.asn.Lookup(parsed, &as); err == nil && as.Number != 0 {
+			info.ISP = "AS" + strconv.FormatUint(uint64(as.Number), 10) + " " + as.Organization
+		}
+	}
+	return info, nil
+}
+
+// Close releases the database files.
+func (m *MMDB) Close() error {
+	if m.asn != nil {
+		m.asn.Close()
+	}
+	return m.city.Close()
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// API keys look like "sk_<prefix>_<secret>"; only the hash is stored.
+const apiKeyPrefix = "sk_"
+
+func newAPIKey() (raw, prefix string, err error) {
+	secret, err := newToken()
+	if err != nil {
+		return "", "", err
+	}
+	prefix = secret[:8]
+	return apiKeyPrefix + prefix + "_" + secret[8:], prefix, nil
+}
+
+// authenticateAPIKey resolves an API key to its owner and checks it carries
+// every required scope. Use is recorded on the key.
+func authenticateAPIKey(c *gin.Context, db *mongo.Database, raw string, scopes []string) *errAuth {
+	ctx := context.Background()
+	now := time.Now()
+
+	var key models.APIKey
+	err := db.Collection("api_keys").FindOne(ctx, bson.M{
+		"keyHash":   HashSecret(raw),
+		"revokedAt": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"expiresAt": bson.M{"$exists": false}},
+			bson.M{"expiresAt": bson.M{"$gt": now}},
+		},
+	}).Decode(&key)
+	if err == mongo.ErrNoDocuments {
+		return &errAuth{http.StatusUnauthorized, "invalid or revoked API key"}
+	}
+	if err != nil {
+		return authDBError(c, err)
+	}
+
+	for _, s := range scopes {
+		if !key.HasScope(s) {
+			return &errAuth{http.StatusForbidden, "API key lacks scope " + s}
+		}
+	}
+
+	var owner models.User
+	err = db.Collection("users").FindOne(ctx, bson.M{
+		"_id":        key.OwnerID,
+		"deletingAs": bson.M{"$exists": false},
+	}).Decode(&owner)
+	if err == mongo.ErrNoDocuments {
+		return &errAuth{http.StatusUnauthorized, "invalid or revoked API key"}
+	}
+	if err != nil {
+		return authDBError(c, err)
+	}
+
+	// A group key acts for the whole group, so it stops working once its
+	// owner is no longer an organiser of that group
+	if key.Group != "" && !models.HasRole(owner.Role, models.RoleAdmin) &&
+		(!models.HasRole(owner.Role, models.RoleOrganiser) || owner.Group != key.Group) {
+		return &errAuth{http.StatusUnauthorized, "invalid or revoked API key"}
+	}
+
+	if _, err := db.Collection("api_keys").UpdateOne(ctx,
+		bson.M{"_id": key.ID},
+		bson.M{"$set": bson.M{"lastUsedAt": now, "lastUsedIp": c.ClientIP()}},
+	); err != nil {
+		return authDBError(c, err)
+	}
+
+	setIdentity(c, owner)
+	c.Set("apiKeyId", key.ID.Hex())
+	c.Set("apiKeyGroup", key.Group)
+	c.Set("mfa", false)
+	return nil
+}
+
+// isAPIKeyRequest reports whether the request was authenticated with an
+// API key rather than a user session.
+func isAPIKeyRequest(c *gin.Context) bool {
+	_, ok := c.Get("apiKeyId")
+	return ok
+}
+
+// CreateAPIKey issues a new key for the authenticated user. Keys for a
+// group or with any write scope need the organiser role. The raw key is
+// only returned here.
+func CreateAPIKey(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		userOID, ok := currentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		var req struct {
+			Name      string   `json:"name"`
+			Scopes    []string `json:"scopes"`
+			Group     string   `json:"group"`
+			ExpiresAt string   `json:"expiresAt"` // optional, RFC3339
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+			return
+		}
+		if len(req.Scopes) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "at least one scope is required"})
+			return
+		}
+		for _, s := range req.Scopes {
+			if !models.ValidScope(s) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scope: " + s})
+				return
+			}
+		}
+		organiser := models.HasRole(c.GetString("role"), models.RoleOrganiser)
+		req.Group = models.NormalizeHandle(req.Group)
+		if req.Group != "" {
+			if !models.ValidHandle(req.Group) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group"})
+				return
+			}
+			if !organiser {
+				c.JSON(http.StatusForbidden, gin.H{"error": "only organisers can create group keys"})
+				return
+			}
+			// Organisers only get keys for their own group
+			if !models.HasRole(c.GetString("role"), models.RoleAdmin) {
+				caller, ok := loadCurrentUser(c, db)
+				if !ok {
+					return
+				}
+				if caller.Group != req.Group {
+					c.JSON(http.StatusForbidden, gin.H{"error": "cannot create keys for another group"})
+					return
+				}
+			}
+		}
+		for _, s := range req.Scopes {
+			if models.IsWriteScope(s) && !organiser {
+				c.JSON(http.StatusForbidden, gin.H{"error": "only organisers can create keys with " + s})
+				return
+			}
+		}
+
+		var expiresAt *time.Time
+		if req.ExpiresAt != "" {
+			t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+			if err != nil || t.Before(time.Now()) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid expiresAt, use a future RFC3339 time"})
+				return
+			}
+			expiresAt = &t
+		}
+
+		raw, prefix, err := newAPIKey()
+		if err != nil {
+			internalError(c, "failed to generate key", err)
+			return
+		}
+
+		key := models.APIKey{
+			Name:      req.Name,
+			Prefix:    prefix,
+			KeyHash:   HashSecret(raw),
+			OwnerID:   userOID,
+			Group:     req.Group,
+			Scopes:    req.Scopes,
+			CreatedAt: time.Now(),
+			ExpiresAt: expiresAt,
+		}
+
+		res, err := db.Collection("api_keys").InsertOne(ctx, key)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		key.ID = res.InsertedID.(primitive.ObjectID)
+
+		writeAudit(ctx, db, "api_key.created", userOID, userOID, c.ClientIP(), key.ID.Hex())
+
+		c.JSON(http.StatusCreated, gin.H{
+			"key":    raw,
+			"apiKey": key,
+		})
+	}
+}
+
+// ListAPIKeys returns the caller's keys. Admins can pass ?all=true.
+func ListAPIKeys(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		userOID, ok := currentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		filter := bson.M{"ownerId": userOID}
+		if c.Query("all") == "true" && models.HasRole(c.GetString("role"), models.RoleAdmin) {
+			filter = bson.M{}
+		}
+
+		cur, err := db.Collection("api_keys").Find(ctx, filter, options.Find().SetSort(bson.M{"createdAt": -1}))
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		defer cur.Close(ctx)
+
+		keys := []models.APIKey{}
+		if err := cur.All(ctx, &keys); err != nil {
+			internalError(c, "decode error", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, keys)
+	}
+}
+
+// RevokeAPIKey disables a key. Owners can revoke their own keys, admins
+// can revoke any key.
+func RevokeAPIKey(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		keyOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid key id"})
+			return
+		}
+
+		userOID, ok := currentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		filter := bson.M{"_id": keyOID, "revokedAt": bson.M{"$exists": false}}
+		if !models.HasRole(c.GetString("role"), models.RoleAdmin) {
+			filter["ownerId"] = userOID
+		}
+
+		res, err := db.Collection("api_keys").UpdateOne(ctx, filter,
+			bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+		)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if res.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "key not found"})
+			return
+		}
+
+		writeAudit(ctx, db, "api_key.revoked", userOID, primitive.NilObjectID, c.ClientIP(), keyOID.Hex())
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
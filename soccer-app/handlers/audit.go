@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"soccer-app/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// writeAudit records a security-relevant action. Failures are logged but
+// never fail the request that triggered them.
+func writeAudit(ctx context.Context, db *mongo.Database, action string, actor, target primitive.ObjectID, ip, details string) {
+	entry := models.AuditEntry{
+		Action:   action,
+		ActorID:  actor,
+		TargetID: target,
+		IP:       ip,
+		Details:  details,
+		Time:     time.Now(),
+	}
+	if _, err := db.Collection("audit_log").InsertOne(ctx, entry); err != nil {
+		slog.Error("audit write failed", "action", action, "error", err)
+	}
+}
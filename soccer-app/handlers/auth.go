@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"soccer-app/logging"
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const sessionTTL = 7 * 24 * time.Hour
+
+// lastSeenInterval limits how often a session's lastSeenAt is rewritten.
+const lastSeenInterval = 5 * time.Minute
+
+// newToken returns a random hex token suitable for sessions and codes.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+const (
+	oneTimeCodeLength = 8
+	// No 0/O or 1/I so codes can be read out loud
+	oneTimeCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// newOneTimeCode returns a short code for a person to type, such as a
+// reset, invite or recovery code.
+func newOneTimeCode() (string, error) {
+	b := make([]byte, oneTimeCodeLength)
+	max := big.NewInt(int64(len(oneTimeCodeAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = oneTimeCodeAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// normalizeOneTimeCode undoes the case and dashes people add when typing
+// a code back.
+func normalizeOneTimeCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// errReverifyRequired is returned by createSession for accounts a
+// suspicious sign-in put on hold.
+var errReverifyRequired = errors.New("account needs re-verification, ask an organiser for a reset code")
+
+// errAccountDeleted is returned by createSession for accounts whose
+// deletion has started.
+var errAccountDeleted = errors.New("account has been deleted")
+
+// createSession stores a new session for the user and returns the raw token.
+// Only the hash of the token is persisted. mfa records whether a second
+// factor was checked when the session was opened; the client's IP and
+// user agent are kept so the user can recognise the session later.
+// Every sign-in path goes through here, so this is where an account on
+// hold is turned away, whichever way it signed in.
+func createSession(ctx context.Context, c *gin.Context, db *mongo.Database, user models.User, mfa bool) (string, error) {
+	if !user.DeletingAs.IsZero() {
+		return "", errAccountDeleted
+	}
+	if user.ReverifyRequired {
+		return "", errReverifyRequired
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:     user.UserID,
+		TokenHash:  HashSecret(token),
+		MFA:        mfa,
+		IP:         c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		RequestID:  c.GetString("requestId"),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(sessionTTL),
+	}
+
+	if _, err := db.Collection("sessions").InsertOne(ctx, session); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// respondSessionError answers a failed createSession, telling held
+// accounts what to do next.
+func respondSessionError(c *gin.Context, err error) {
+	if errors.Is(err, errReverifyRequired) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":            err.Error(),
+			"reverifyRequired": true,
+		})
+		return
+	}
+	if errors.Is(err, errAccountDeleted) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	internalError(c, "failed to create session", err)
+}
+
+// bearerToken returns the credential from "Authorization: Bearer" or, for
+// integrations that can only set custom headers, "X-API-Key".
+func bearerToken(c *gin.Context) string {
+	if k := c.GetHeader("X-API-Key"); k != "" {
+		return strings.TrimSpace(k)
+	}
+	h := c.GetHeader("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+}
+
+// errAuth carries the response for a credential that could not be
+// resolved to a user.
+type errAuth struct {
+	status int
+	msg    string
+}
+
+var errInvalidSession = &errAuth{http.StatusUnauthorized, "invalid or expired session"}
+
+// authDBError logs a failed credential lookup and reports it as a 500.
+func authDBError(c *gin.Context, err error) *errAuth {
+	logging.From(c).Error("credential lookup failed", "error", err)
+	return &errAuth{http.StatusInternalServerError, "db error"}
+}
+
+// authenticate resolves the bearer credential to a user and stores
+// "userId", "role", "mfa" and either "sessionId" or "apiKeyId" in the gin
+// context. API keys are only accepted when they carry every one of scopes;
+// with no scopes only session tokens are accepted.
+func authenticate(c *gin.Context, db *mongo.Database, token string, scopes []string) *errAuth {
+	ctx := context.Background()
+
+	if strings.HasPrefix(token, apiKeyPrefix) {
+		if len(scopes) == 0 {
+			return &errAuth{http.StatusForbidden, "API keys are not accepted here"}
+		}
+		return authenticateAPIKey(c, db, token, scopes)
+	}
+
+	var session models.Session
+	err := db.Collection("sessions").FindOne(ctx, bson.M{
+		"tokenHash": HashSecret(token),
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return errInvalidSession
+	}
+	if err != nil {
+		return authDBError(c, err)
+	}
+
+	var user models.User
+	err = db.Collection("users").FindOne(ctx, bson.M{
+		"_id":        session.UserID,
+		"deletingAs": bson.M{"$exists": false},
+	}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return errInvalidSession
+	}
+	if err != nil {
+		return authDBError(c, err)
+	}
+
+	// Best effort: a stale lastSeenAt is no reason to turn the request away
+	if time.Since(session.LastSeenAt) > lastSeenInterval {
+		if _, err := db.Collection("sessions").UpdateOne(ctx,
+			bson.M{"_id": session.ID},
+			bson.M{"$set": bson.M{"lastSeenAt": time.Now(), "ip": c.ClientIP()}},
+		); err != nil {
+			logging.From(c).Warn("session last seen update failed", "error", err)
+		}
+	}
+
+	setIdentity(c, user)
+	c.Set("sessionId", session.ID.Hex())
+	c.Set("mfa", session.MFA && user.TOTPEnabled)
+	return nil
+}
+
+func setIdentity(c *gin.Context, user models.User) {
+	role := user.Role
+	if role == "" {
+		role = models.RolePlayer
+	}
+
+	c.Set("userId", user.UserID.Hex())
+	c.Set("role", role)
+}
+
+// AuthRequired rejects requests without a valid credential. Pass scopes to
+// also accept API keys that carry them.
+func AuthRequired(db *mongo.Database, scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		if e := authenticate(c, db, token, scopes); e != nil {
+			c.AbortWithStatusJSON(e.status, gin.H{"error": e.msg})
+			return
+		}
+		c.Next()
+	}
+}
+
+// OptionalAuth is like AuthRequired but lets anonymous requests through so
+// the handler can fall back to another way of identifying the caller.
+// A credential that is present but invalid is still rejected.
+func OptionalAuth(db *mongo.Database, scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		if e := authenticate(c, db, token, scopes); e != nil {
+			c.AbortWithStatusJSON(e.status, gin.H{"error": e.msg})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole rejects requests whose authenticated role is below role.
+// Admin actions additionally need a session opened with TOTP.
+// It must run after AuthRequired.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !models.HasRole(c.GetString("role"), role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			return
+		}
+		if role == models.RoleAdmin && !c.GetBool("mfa") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "two-factor authentication required for admin actions"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// currentUserID returns the authenticated user's ID set by AuthRequired.
+func currentUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	oid, err := primitive.ObjectIDFromHex(c.GetString("userId"))
+	if err != nil {
+		return primitive.NilObjectID, false
+	}
+	return oid, true
+}
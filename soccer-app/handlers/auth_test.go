@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCreateSessionRefusesHeldAccount(t *testing.T) {
+	db := testDB(t)
+	held := insertUser(t, db, models.User{Username: "held", ReverifyRequired: true}, "x")
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/", nil)
+
+	if _, err := createSession(context.Background(), c, db, held, false); !errors.Is(err, errReverifyRequired) {
+		t.Fatalf("err = %v, want errReverifyRequired", err)
+	}
+	n, err := db.Collection("sessions").CountDocuments(context.Background(), bson.M{"userId": held.UserID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("%d sessions created for a held account", n)
+	}
+}
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"net/http"
+
+	"soccer-app/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// internalError logs err against the request and answers 500 with msg,
+// which is all the client gets to see.
+func internalError(c *gin.Context, msg string, err error) {
+	logging.From(c).Error(msg, "error", err, "route", c.FullPath())
+	c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"soccer-app/logging"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// readyPingTimeout keeps a readiness probe from hanging on a stuck database.
+const readyPingTimeout = 2 * time.Second
+
+// Healthz reports that the process is up and serving. It checks nothing
+// else, so a database outage doesn't get the server restarted.
+func Healthz() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+}
+
+// Readyz reports whether the server should be sent traffic: not once
+// draining is set at shutdown, and not while Mongo doesn't answer a ping.
+func Readyz(db *mongo.Database, draining *atomic.Bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if draining.Load() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting down"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), readyPingTimeout)
+		defer cancel()
+		if err := db.Client().Ping(ctx, nil); err != nil {
+			logging.From(c).Warn("readiness ping failed", "error", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "database unavailable"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	}
+}
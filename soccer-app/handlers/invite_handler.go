@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const defaultInviteTTL = 7 * 24 * time.Hour
+
+// groupInviteOnly reports whether registering in group needs an invite.
+// Groups without a settings document are open.
+func groupInviteOnly(ctx context.Context, db *mongo.Database, group string) (bool, error) {
+	var g models.Group
+	err := db.Collection("groups").FindOne(ctx, bson.M{"name": group}).Decode(&g)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return g.InviteOnly, nil
+}
+
+// redeemInvite consumes one use of an invite code. It returns
+// mongo.ErrNoDocuments if the code is unknown, revoked, expired or used up.
+func redeemInvite(ctx context.Context, db *mongo.Database, code string) (models.Invite, error) {
+	var inv models.Invite
+	err := db.Collection("invites").FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"codeHash":  HashSecret(normalizeOneTimeCode(code)),
+			"revokedAt": bson.M{"$exists": false},
+			"expiresAt": bson.M{"$gt": time.Now()},
+			"$expr":     bson.M{"$lt": bson.A{"$uses", "$maxUses"}},
+		},
+		bson.M{"$inc": bson.M{"uses": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&inv)
+	return inv, err
+}
+
+// releaseInvite gives back a use taken by redeemInvite when registration
+// fails afterwards.
+func releaseInvite(ctx context.Context, db *mongo.Database, id primitive.ObjectID) {
+	if _, err := db.Collection("invites").UpdateOne(ctx,
+		bson.M{"_id": id, "uses": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"uses": -1}},
+	); err != nil {
+		slog.Error("invite release failed", "inviteId", id.Hex(), "error", err)
+	}
+}
+
+// inviteScope returns the only group whose invites the caller may manage,
+// or "" for admins, who manage all of them. It writes the response when
+// ok is false.
+func inviteScope(c *gin.Context, db *mongo.Database) (group string, ok bool) {
+	if models.HasRole(c.GetString("role"), models.RoleAdmin) {
+		return "", true
+	}
+	caller, ok := loadCurrentUser(c, db)
+	if !ok {
+		return "", false
+	}
+	if caller.Group == "" {
+		return models.DefaultGroup, true
+	}
+	return caller.Group, true
+}
+
+// CreateInvite issues an invite code for a group. Organiser only, for
+// the organiser's own group unless admin; the pre-assigned role can't be
+// higher than the creator's own.
+func CreateInvite(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		var req struct {
+			Group          string `json:"group"`
+			Role           string `json:"role"`
+			MaxUses        int    `json:"maxUses"`        // default 1 (single use)
+			ExpiresInHours int    `json:"expiresInHours"` // default 7 days
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		req.Group = strings.ToLower(strings.TrimSpace(req.Group))
+		if !models.ValidHandle(req.Group) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group is required"})
+			return
+		}
+		if scope, ok := inviteScope(c, db); !ok {
+			return
+		} else if scope != "" && scope != req.Group {
+			c.JSON(http.StatusForbidden, gin.H{"error": "cannot invite to another group"})
+			return
+		}
+
+		if req.Role != "" {
+			if !models.ValidRole(req.Role) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+				return
+			}
+			if !models.HasRole(c.GetString("role"), req.Role) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "cannot invite with a role above your own"})
+				return
+			}
+		}
+		if req.MaxUses <= 0 {
+			req.MaxUses = 1
+		}
+		ttl := defaultInviteTTL
+		if req.ExpiresInHours > 0 {
+			ttl = time.Duration(req.ExpiresInHours) * time.Hour
+		}
+
+		code, err := newOneTimeCode()
+		if err != nil {
+			internalError(c, "failed to generate code", err)
+			return
+		}
+
+		creator, _ := currentUserID(c)
+		now := time.Now()
+		inv := models.Invite{
+			CodeHash:  HashSecret(code),
+			Group:     req.Group,
+			Role:      req.Role,
+			MaxUses:   req.MaxUses,
+			CreatedBy: creator,
+			CreatedAt: now,
+			ExpiresAt: now.Add(ttl),
+		}
+
+		res, err := db.Collection("invites").InsertOne(ctx, inv)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		inv.ID = res.InsertedID.(primitive.ObjectID)
+
+		writeAudit(ctx, db, "invite.created", creator, primitive.NilObjectID, c.ClientIP(), inv.ID.Hex())
+
+		c.JSON(http.StatusCreated, gin.H{
+			"code":   code,
+			"invite": inv,
+		})
+	}
+}
+
+// ListInvites returns invites that are still usable. Organiser only, and
+// limited to the organiser's own group unless admin.
+func ListInvites(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		filter := bson.M{
+			"revokedAt": bson.M{"$exists": false},
+			"expiresAt": bson.M{"$gt": time.Now()},
+		}
+		scope, ok := inviteScope(c, db)
+		if !ok {
+			return
+		}
+		if scope != "" {
+			filter["group"] = scope
+		}
+
+		cur, err := db.Collection("invites").Find(
+			ctx,
+			filter,
+			options.Find().SetSort(bson.M{"createdAt": -1}),
+		)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		defer cur.Close(ctx)
+
+		invites := []models.Invite{}
+		if err := cur.All(ctx, &invites); err != nil {
+			internalError(c, "decode error", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, invites)
+	}
+}
+
+// RevokeInvite stops an invite code from being used. Organiser only, and
+// limited to the organiser's own group unless admin.
+func RevokeInvite(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		inviteOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invite id"})
+			return
+		}
+
+		filter := bson.M{"_id": inviteOID, "revokedAt": bson.M{"$exists": false}}
+		scope, ok := inviteScope(c, db)
+		if !ok {
+			return
+		}
+		if scope != "" {
+			filter["group"] = scope
+		}
+
+		res, err := db.Collection("invites").UpdateOne(ctx,
+			filter,
+			bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+		)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if res.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invite not found"})
+			return
+		}
+
+		actor, _ := currentUserID(c)
+		writeAudit(ctx, db, "invite.revoked", actor, primitive.NilObjectID, c.ClientIP(), inviteOID.Hex())
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// UpdateGroup creates or updates a group's settings, such as whether
+// registration is invite-only. Admin only.
+func UpdateGroup(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := strings.ToLower(strings.TrimSpace(c.Param("name")))
+		if !models.ValidHandle(name) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group name"})
+			return
+		}
+
+		var req struct {
+			DisplayName *string `json:"displayName"`
+			InviteOnly  *bool   `json:"inviteOnly"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		set := bson.M{}
+		if req.DisplayName != nil {
+			set["displayName"] = *req.DisplayName
+		}
+		if req.InviteOnly != nil {
+			set["inviteOnly"] = *req.InviteOnly
+		}
+
+		update := bson.M{"$setOnInsert": bson.M{"name": name, "createdAt": time.Now()}}
+		if len(set) > 0 {
+			update["$set"] = set
+		}
+
+		var g models.Group
+		err := db.Collection("groups").FindOneAndUpdate(
+			context.Background(),
+			bson.M{"name": name},
+			update,
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&g)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, g)
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// insertInvite stores a single-use invite for group and returns its code.
+func insertInvite(t *testing.T, db *mongo.Database, group string) string {
+	t.Helper()
+	code, err := newOneTimeCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	_, err = db.Collection("invites").InsertOne(context.Background(), models.Invite{
+		CodeHash:  HashSecret(normalizeOneTimeCode(code)),
+		Group:     group,
+		MaxUses:   1,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestCreateInviteNeedsGroup(t *testing.T) {
+	db := testDB(t)
+	org := insertUser(t, db, models.User{Username: "olive", Group: "tuesday", Role: models.RoleOrganiser}, "x")
+
+	r := gin.New()
+	r.POST("/invites", AuthRequired(db), CreateInvite(db))
+	token := insertSession(t, db, org)
+
+	if w := doJSON(t, r, "POST", "/invites", token, gin.H{}); w.Code != http.StatusBadRequest {
+		t.Fatalf("no group: status = %d, want 400", w.Code)
+	}
+	if w := doJSON(t, r, "POST", "/invites", token, gin.H{"group": "tuesday"}); w.Code != http.StatusCreated {
+		t.Fatalf("with group: status = %d, want 201: %s", w.Code, w.Body)
+	}
+	if w := doJSON(t, r, "POST", "/invites", token, gin.H{"group": "sunday"}); w.Code != http.StatusForbidden {
+		t.Fatalf("another group: status = %d, want 403: %s", w.Code, w.Body)
+	}
+}
+
+func TestListInvitesOwnGroup(t *testing.T) {
+	db := testDB(t)
+	org := insertUser(t, db, models.User{Username: "olive", Group: "tuesday", Role: models.RoleOrganiser}, "x")
+	admin := insertUser(t, db, models.User{Username: "ada", Role: models.RoleAdmin}, "y")
+	insertInvite(t, db, "tuesday")
+	insertInvite(t, db, "sunday")
+
+	r := gin.New()
+	r.GET("/invites", AuthRequired(db), ListInvites(db))
+
+	for _, tc := range []struct {
+		name string
+		user models.User
+		want int
+	}{
+		{"organiser", org, 1},
+		{"admin", admin, 2},
+	} {
+		w := doJSON(t, r, "GET", "/invites", insertSession(t, db, tc.user), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200: %s", tc.name, w.Code, w.Body)
+		}
+		var invites []models.Invite
+		if err := json.Unmarshal(w.Body.Bytes(), &invites); err != nil {
+			t.Fatal(err)
+		}
+		if len(invites) != tc.want {
+			t.Fatalf("%s: %d invites, want %d", tc.name, len(invites), tc.want)
+		}
+	}
+}
+
+func TestRegisterInviteOnlyGroup(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	if _, err := db.Collection("groups").InsertOne(ctx, models.Group{Name: "tuesday", InviteOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.POST("/register", RegisterUser(db))
+	register := func(username, invite string) int {
+		return doJSON(t, r, "POST", "/register", "", gin.H{
+			"username":   username,
+			"firstName":  "Test",
+			"lastName":   "User",
+			"secret":     "s3cret",
+			"group":      "tuesday",
+			"inviteCode": invite,
+			"skills":     []models.Skill{{Name: "speed", Value: 5}},
+		}).Code
+	}
+
+	if got := register("nocode", ""); got != http.StatusForbidden {
+		t.Fatalf("no invite: status = %d, want 403", got)
+	}
+
+	groupless := insertInvite(t, db, "")
+	if got := register("groupless", groupless); got != http.StatusForbidden {
+		t.Fatalf("group-less invite: status = %d, want 403", got)
+	}
+	var inv models.Invite
+	if err := db.Collection("invites").FindOne(ctx, bson.M{"codeHash": HashSecret(normalizeOneTimeCode(groupless))}).Decode(&inv); err != nil {
+		t.Fatal(err)
+	}
+	if inv.Uses != 0 {
+		t.Fatalf("refused invite kept %d uses, want 0", inv.Uses)
+	}
+
+	if got := register("sunday", insertInvite(t, db, "sunday")); got != http.StatusForbidden {
+		t.Fatalf("other group's invite: status = %d, want 403", got)
+	}
+	if got := register("invited", insertInvite(t, db, "tuesday")); got != http.StatusCreated {
+		t.Fatalf("group invite: status = %d, want 201", got)
+	}
+}
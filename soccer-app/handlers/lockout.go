@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// Failures allowed before the backoff kicks in
+	accountFreeAttempts = 3
+	ipFreeAttempts      = 20
+
+	lockoutBase = 30 * time.Second
+	lockoutMax  = time.Hour
+
+	// Failures older than this no longer count
+	attemptWindow = 15 * time.Minute
+)
+
+func accountKey(username string) string {
+	return "account:" + models.NormalizeHandle(username)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// lockoutDelay doubles the lock for every failure past the free attempts.
+func lockoutDelay(failures, free int) time.Duration {
+	over := failures - free
+	if over <= 0 {
+		return 0
+	}
+	d := lockoutBase
+	for i := 1; i < over && d < lockoutMax; i++ {
+		d *= 2
+	}
+	if d > lockoutMax {
+		d = lockoutMax
+	}
+	return d
+}
+
+// lockedFor returns how long the longest lock among keys still has to run.
+func lockedFor(ctx context.Context, db *mongo.Database, keys ...string) (time.Duration, error) {
+	cur, err := db.Collection("login_attempts").Find(ctx, bson.M{
+		"_id":         bson.M{"$in": keys},
+		"lockedUntil": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	var attempts []models.LoginAttempt
+	if err := cur.All(ctx, &attempts); err != nil {
+		return 0, err
+	}
+
+	var wait time.Duration
+	for _, a := range attempts {
+		if d := time.Until(a.LockedUntil); d > wait {
+			wait = d
+		}
+	}
+	return wait, nil
+}
+
+// recordFailure bumps the failure count for key and locks it once the
+// free attempts are used up. The count is incremented atomically so
+// parallel guesses can't read the same value and slip past the backoff.
+func recordFailure(ctx context.Context, db *mongo.Database, key string, free int) error {
+	coll := db.Collection("login_attempts")
+	now := time.Now()
+
+	// 1️⃣ Forget failures that have aged out (and aren't holding a lock)
+	if _, err := coll.UpdateOne(ctx,
+		bson.M{
+			"_id":         key,
+			"lastFailure": bson.M{"$lt": now.Add(-attemptWindow)},
+			"lockedUntil": bson.M{"$lt": now},
+		},
+		bson.M{"$set": bson.M{"failures": 0}},
+	); err != nil {
+		return err
+	}
+
+	// 2️⃣ Count this one
+	var attempt models.LoginAttempt
+	err := coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": key},
+		bson.M{
+			"$inc":         bson.M{"failures": 1},
+			"$set":         bson.M{"lastFailure": now},
+			"$setOnInsert": bson.M{"lockedUntil": time.Time{}},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempt)
+	if err != nil {
+		return err
+	}
+
+	// 3️⃣ Lock from the count we got; $max keeps a longer lock set by a
+	// parallel failure
+	if d := lockoutDelay(attempt.Failures, free); d > 0 {
+		_, err = coll.UpdateOne(ctx,
+			bson.M{"_id": key},
+			bson.M{"$max": bson.M{"lockedUntil": now.Add(d)}},
+		)
+	}
+	return err
+}
+
+// recordFailures counts a failed sign-in against both the account and the
+// client.
+func recordFailures(ctx context.Context, db *mongo.Database, acctKey, clientKey string) error {
+	if err := recordFailure(ctx, db, acctKey, accountFreeAttempts); err != nil {
+		return err
+	}
+	return recordFailure(ctx, db, clientKey, ipFreeAttempts)
+}
+
+func clearFailures(ctx context.Context, db *mongo.Database, key string) error {
+	_, err := db.Collection("login_attempts").DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
+
+func respondLocked(c *gin.Context, wait time.Duration) {
+	secs := int(wait.Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(secs))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":      "too many failed attempts, try again later",
+		"retryAfter": secs,
+	})
+}
+
+// UnlockUser clears the failed-login lock on a user's account. Admin only.
+func UnlockUser(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		userOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return
+		}
+
+		var user models.User
+		err = db.Collection("users").FindOne(ctx, bson.M{"_id": userOID}).Decode(&user)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		if err := clearFailures(ctx, db, accountKey(user.Username)); err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
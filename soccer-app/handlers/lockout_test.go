@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"soccer-app/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestLockoutDelay(t *testing.T) {
+	cases := []struct {
+		failures, free int
+		want           time.Duration
+	}{
+		{0, 3, 0},
+		{3, 3, 0},
+		{4, 3, lockoutBase},
+		{5, 3, 2 * lockoutBase},
+		{6, 3, 4 * lockoutBase},
+		{100, 3, lockoutMax},
+	}
+	for _, tc := range cases {
+		if got := lockoutDelay(tc.failures, tc.free); got != tc.want {
+			t.Errorf("lockoutDelay(%d, %d) = %v, want %v", tc.failures, tc.free, got, tc.want)
+		}
+	}
+}
+
+func TestRecordFailureConcurrent(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	key := accountKey("alice")
+
+	// Parallel guesses must each be counted
+	const guesses = 20
+	var wg sync.WaitGroup
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := recordFailure(ctx, db, key, accountFreeAttempts); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var attempt models.LoginAttempt
+	if err := db.Collection("login_attempts").FindOne(ctx, bson.M{"_id": key}).Decode(&attempt); err != nil {
+		t.Fatal(err)
+	}
+	if attempt.Failures != guesses {
+		t.Fatalf("failures = %d, want %d", attempt.Failures, guesses)
+	}
+
+	wait, err := lockedFor(ctx, db, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := lockoutDelay(guesses, accountFreeAttempts); wait < want-time.Minute {
+		t.Fatalf("locked for %v, want about %v", wait, want)
+	}
+}
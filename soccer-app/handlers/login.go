@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
 
 	"soccer-app/models"
@@ -15,9 +16,10 @@ import (
 func LoginUser(db *mongo.Database) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
-			FirstName string `json:"firstName"`
-			LastName  string `json:"lastName"`
-			Secret    string `json:"secret"`
+			Username     string `json:"username"`
+			Secret       string `json:"secret"`
+			TOTPCode     string `json:"totpCode"`
+			RecoveryCode string `json:"recoveryCode"`
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -25,42 +27,106 @@ func LoginUser(db *mongo.Database) gin.HandlerFunc {
 			return
 		}
 
-		if req.FirstName == "" || req.LastName == "" || req.Secret == "" {
+		req.Username = models.NormalizeHandle(req.Username)
+		if req.Username == "" || req.Secret == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "missing fields"})
 			return
 		}
 
+		ctx := context.Background()
+		acctKey := accountKey(req.Username)
+		clientKey := ipKey(c.ClientIP())
+
+		// ⏳ Refuse while the account or the client is locked out
+		wait, err := lockedFor(ctx, db, acctKey, clientKey)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if wait > 0 {
+			respondLocked(c, wait)
+			return
+		}
+
 		var user models.User
-		err := db.Collection("users").FindOne(
-			context.Background(),
-			bson.M{
-				"firstName": req.FirstName,
-				"lastName":  req.LastName,
-			},
+		err = db.Collection("users").FindOne(
+			ctx,
+			bson.M{"username": req.Username},
 		).Decode(&user)
 
-		if err == mongo.ErrNoDocuments {
-			// ❌ Not registered
-			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		if err != nil && err != mongo.ErrNoDocuments {
+			internalError(c, "db error", err)
 			return
 		}
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+
+		// 🔐 Verify secret (unknown users and wrong secrets look the same)
+		if err == mongo.ErrNoDocuments ||
+			subtle.ConstantTimeCompare([]byte(user.SecretHash), []byte(HashSecret(req.Secret))) != 1 {
+			if err := recordFailures(ctx, db, acctKey, clientKey); err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 			return
 		}
 
-		// 🔐 Verify secret
-		if user.SecretHash != HashSecret(req.Secret) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "invalid secret"})
+		// 🔑 Second factor for users who enrolled in TOTP
+		if user.TOTPEnabled {
+			if req.TOTPCode == "" && req.RecoveryCode == "" {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error":        "two-factor code required",
+					"totpRequired": true,
+				})
+				return
+			}
+
+			valid, err := verifySecondFactor(ctx, db, user, req.TOTPCode, req.RecoveryCode)
+			if err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+			if !valid {
+				if err := recordFailures(ctx, db, acctKey, clientKey); err != nil {
+					internalError(c, "db error", err)
+					return
+				}
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+				return
+			}
+		}
+
+		if err := clearFailures(ctx, db, acctKey); err != nil {
+			internalError(c, "db error", err)
 			return
 		}
 
+		// 🚩 Refused here if a suspicious sign-in put the account on hold
+		token, err := createSession(ctx, c, db, user, user.TOTPEnabled)
+		if err != nil {
+			respondSessionError(c, err)
+			return
+		}
+		markSignedIn(c, user.UserID)
+
+		role := user.Role
+		if role == "" {
+			role = models.RolePlayer
+		}
+
+		// Admins must enrol in TOTP before admin endpoints accept them
+		enrolRequired := role == models.RoleAdmin && !user.TOTPEnabled
+
 		// ✅ Login success
 		c.JSON(http.StatusOK, gin.H{
-			"firstName": user.FirstName,
-			"lastName":  user.LastName,
-			"position":  user.Position,
-			"skills":    user.Skills,
+			"token":                 token,
+			"userId":                user.UserID.Hex(),
+			"role":                  role,
+			"totpEnrolmentRequired": enrolRequired,
+			"username":              user.Username,
+			"firstName":             user.FirstName,
+			"lastName":              user.LastName,
+			"position":              user.Position,
+			"skills":                user.Skills,
 		})
 	}
 }
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"soccer-app/config"
+	"soccer-app/geo"
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Jumps shorter than this are within geolocation error and never count as
+// impossible travel.
+const minTravelKm = 100
+
+// LoginDetector compares each sign-in with the user's earlier ones and
+// raises alerts for impossible travel, new countries and new devices.
+type LoginDetector struct {
+	db     *mongo.Database
+	cfg    config.LoginAlerts
+	client *http.Client
+}
+
+func NewLoginDetector(db *mongo.Database, cfg config.LoginAlerts) *LoginDetector {
+	return &LoginDetector{
+		db:     db,
+		cfg:    cfg,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Check inspects a stored login event. It is meant to run as a
+// LoginEventQueue hook, off the request path.
+func (d *LoginDetector) Check(ctx context.Context, e models.LoginEvent) {
+	// Only sign-ins by a known user have a history to compare; resets,
+	// registrations and other logged requests don't count
+	if !e.Success || !e.SignIn || e.UserID.IsZero() {
+		return
+	}
+
+	// History from before the latest hold or reset may be the intruder's
+	var user models.User
+	err := d.db.Collection("users").FindOne(ctx, bson.M{"_id": e.UserID},
+		options.FindOne().SetProjection(bson.M{"loginHistoryFrom": 1}),
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return
+	}
+	if err != nil {
+		slog.Error("login detector: user lookup failed", "userId", e.UserID.Hex(), "error", err)
+		return
+	}
+
+	cur, err := d.db.Collection("login_events").Find(ctx,
+		bson.M{
+			"userId":  e.UserID,
+			"success": true,
+			"signIn":  true,
+			"_id":     bson.M{"$ne": e.ID},
+			"time":    bson.M{"$lte": e.Time, "$gt": user.LoginHistoryFrom},
+		},
+		options.Find().SetSort(bson.D{{Key: "time", Value: -1}}).SetLimit(500),
+	)
+	if err != nil {
+		slog.Error("login detector: history lookup failed", "userId", e.UserID.Hex(), "error", err)
+		return
+	}
+	var history []models.LoginEvent
+	if err := cur.All(ctx, &history); err != nil {
+		slog.Error("login detector: history decode failed", "userId", e.UserID.Hex(), "error", err)
+		return
+	}
+	if len(history) == 0 {
+		return // first sign-in, nothing to compare with
+	}
+
+	var alerts []models.LoginAlert
+	alert := func(kind, details string) {
+		alerts = append(alerts, models.LoginAlert{
+			UserID:    e.UserID,
+			Username:  e.Username,
+			EventID:   e.ID,
+			Kind:      kind,
+			Details:   details,
+			IP:        e.IP,
+			Country:   e.Country,
+			City:      e.City,
+			UserAgent: e.UserAgent,
+			CreatedAt: time.Now(),
+		})
+	}
+
+	// 1️⃣ Impossible travel from the last located sign-in
+	for _, prev := range history {
+		km, ok := geo.DistanceKm(prev.Lat, prev.Lng, e.Lat, e.Lng)
+		if !ok {
+			continue
+		}
+		hours := e.Time.Sub(prev.Time).Hours()
+		if km >= minTravelKm && (hours <= 0 || km/hours > d.cfg.MaxTravelKmh) {
+			alert(models.AlertImpossibleTravel, fmt.Sprintf(
+				"%.0f km from %s, %s in %s", km, prev.City, prev.Country, e.Time.Sub(prev.Time).Round(time.Minute)))
+		}
+		break
+	}
+
+	// 2️⃣ New country / new device
+	device := models.ParseUserAgent(e.UserAgent)
+	knownCountry, knownDevice, located := false, false, false
+	for _, prev := range history {
+		if prev.Country != "" {
+			located = true
+		}
+		if prev.Country == e.Country {
+			knownCountry = true
+		}
+		if models.ParseUserAgent(prev.UserAgent) == device {
+			knownDevice = true
+		}
+	}
+	if e.Country != "" && located && !knownCountry {
+		alert(models.AlertNewCountry, "first sign-in from "+e.Country)
+	}
+	if !knownDevice {
+		alert(models.AlertNewDevice, "first sign-in from "+device.String())
+	}
+
+	for _, a := range alerts {
+		d.raise(ctx, a)
+	}
+}
+
+func (d *LoginDetector) raise(ctx context.Context, a models.LoginAlert) {
+	res, err := d.db.Collection("login_alerts").InsertOne(ctx, a)
+	if err != nil {
+		slog.Error("login alert write failed", "kind", a.Kind, "userId", a.UserID.Hex(), "error", err)
+		return
+	}
+	a.ID = res.InsertedID.(primitive.ObjectID)
+	writeAudit(ctx, d.db, "login_alert."+a.Kind, primitive.NilObjectID, a.UserID, a.IP, a.Details)
+
+	// Someone else may be using the account: make them prove who they are
+	if a.Kind == models.AlertImpossibleTravel && d.cfg.RequireReverification {
+		if err := requireReverification(ctx, d.db, a.UserID); err != nil {
+			slog.Error("login alert reverification failed", "userId", a.UserID.Hex(), "error", err)
+		}
+	}
+
+	if d.cfg.WebhookURL != "" {
+		d.notify(ctx, a)
+	}
+}
+
+// requireReverification signs the user out everywhere and blocks password
+// login until an organiser-issued reset code is redeemed.
+func requireReverification(ctx context.Context, db *mongo.Database, userID primitive.ObjectID) error {
+	if _, err := db.Collection("users").UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"reverifyRequired": true, "loginHistoryFrom": time.Now()}},
+	); err != nil {
+		return err
+	}
+	_, err := db.Collection("sessions").DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
+
+func (d *LoginDetector) notify(ctx context.Context, a models.LoginAlert) {
+	body, err := json.Marshal(gin.H{
+		"text":  fmt.Sprintf("Suspicious sign-in for %s: %s (%s)", a.Username, a.Details, a.Kind),
+		"alert": a,
+	})
+	if err != nil {
+		slog.Error("login alert webhook: encode failed", "error", err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		slog.Error("login alert webhook: bad request", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		slog.Warn("login alert webhook failed", "alertId", a.ID.Hex(), "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		slog.Warn("login alert webhook rejected", "alertId", a.ID.Hex(), "status", resp.StatusCode)
+	}
+}
+
+// ListLoginAlerts returns unresolved alerts, or all with ?all=true.
+// Organiser only.
+func ListLoginAlerts(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := bson.M{"resolvedAt": bson.M{"$exists": false}}
+		if c.Query("all") == "true" {
+			filter = bson.M{}
+		}
+		listLoginAlerts(c, db, filter)
+	}
+}
+
+// ListMyLoginAlerts returns alerts raised for the authenticated user.
+func ListMyLoginAlerts(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userOID, ok := currentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+		listLoginAlerts(c, db, bson.M{"userId": userOID})
+	}
+}
+
+func listLoginAlerts(c *gin.Context, db *mongo.Database, filter bson.M) {
+	ctx := context.Background()
+
+	cur, err := db.Collection("login_alerts").Find(ctx, filter,
+		options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(maxPageSize),
+	)
+	if err != nil {
+		internalError(c, "db error", err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	alerts := []models.LoginAlert{}
+	if err := cur.All(ctx, &alerts); err != nil {
+		internalError(c, "decode error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
+
+// ResolveLoginAlert marks an alert as dealt with. Organiser only.
+func ResolveLoginAlert(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		alertOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid alert id"})
+			return
+		}
+
+		actor, _ := currentUserID(c)
+		res, err := db.Collection("login_alerts").UpdateOne(ctx,
+			bson.M{"_id": alertOID, "resolvedAt": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"resolvedAt": time.Now(), "resolvedBy": actor}},
+		)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if res.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "alert not found"})
+			return
+		}
+
+		writeAudit(ctx, db, "login_alert.resolved", actor, primitive.NilObjectID, c.ClientIP(), alertOID.Hex())
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"soccer-app/config"
+	"soccer-app/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestLoginDetectorIgnoresHistoryBeforeReset(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	d := NewLoginDetector(db, config.LoginAlerts{MaxTravelKmh: 900, RequireReverification: true})
+
+	now := time.Now().UTC()
+	const ua = "Mozilla/5.0 (X11; Linux x86_64) Firefox/120.0"
+	signIn := func(user models.User, at time.Time, country, lat, lng string, signIn bool) models.LoginEvent {
+		e := models.LoginEvent{
+			ID: primitive.NewObjectID(), Time: at, UserID: user.UserID, Success: true, SignIn: signIn,
+			UserAgent: ua, Country: country, Lat: lat, Lng: lng,
+		}
+		if _, err := db.Collection("login_events").InsertOne(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	held := func(user models.User) bool {
+		var u models.User
+		if err := db.Collection("users").FindOne(ctx, bson.M{"_id": user.UserID}).Decode(&u); err != nil {
+			t.Fatal(err)
+		}
+		return u.ReverifyRequired
+	}
+
+	// Without a reset, London then Sydney an hour later is impossible travel
+	ann := insertUser(t, db, models.User{Username: "ann"}, "x")
+	signIn(ann, now.Add(-time.Hour), "GB", "51.5", "-0.1", true)
+	d.Check(ctx, signIn(ann, now, "AU", "-33.9", "151.2", true))
+	if !held(ann) {
+		t.Fatal("impossible travel did not put the account on hold")
+	}
+
+	// The intruder's sign-in predates the reset, so the owner's return home
+	// is compared with nothing
+	bea := insertUser(t, db, models.User{Username: "bea", LoginHistoryFrom: now.Add(-30 * time.Minute)}, "x")
+	signIn(bea, now.Add(-time.Hour), "AU", "-33.9", "151.2", true)
+	signIn(bea, now.Add(-20*time.Minute), "GB", "51.5", "-0.1", false) // the reset itself
+	d.Check(ctx, signIn(bea, now, "GB", "51.5", "-0.1", true))
+	if held(bea) {
+		t.Fatal("sign-in after a reset was compared with the intruder's")
+	}
+}
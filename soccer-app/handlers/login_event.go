@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"soccer-app/config"
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Bodies larger than this are not inspected for a username.
+const maxLoggedBody = 64 << 10
+
+// markSignedIn records that the request opened a session for the user, so
+// RecordLoginEvents attributes the event and LoginDetector compares it.
+func markSignedIn(c *gin.Context, userID primitive.ObjectID) {
+	markIdentified(c, userID)
+	c.Set("signIn", true)
+}
+
+// markIdentified records who a request such as a registration or secret
+// reset resolved to without counting it as a sign-in.
+func markIdentified(c *gin.Context, userID primitive.ObjectID) {
+	c.Set("userId", userID.Hex())
+}
+
+// RecordLoginEvents queues an access-log entry for each request it wraps,
+// after the handler has run so the outcome and user are known.
+func RecordLoginEvents(q *LoginEventQueue, cfg config.AccessLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Skip CORS preflight
+		if c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		// Peek at the body for the name the client tried to sign in as,
+		// leaving it intact for the handler. A chunked body has no length
+		// up front, so whatever the limit cut off is put back behind the
+		// part that was read.
+		var body struct {
+			Username  string `json:"username"`
+			FirstName string `json:"firstName"`
+			LastName  string `json:"lastName"`
+		}
+		if c.Request.Body != nil && c.Request.ContentLength <= maxLoggedBody {
+			rest := c.Request.Body
+			raw, err := io.ReadAll(io.LimitReader(rest, maxLoggedBody))
+			if err == nil && len(raw) < maxLoggedBody {
+				_ = json.Unmarshal(raw, &body)
+			}
+			c.Request.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(raw), rest), rest}
+		}
+
+		c.Next()
+
+		now := time.Now().UTC()
+		event := models.LoginEvent{
+			Time:      now,
+			Username:  models.NormalizeHandle(body.Username),
+			FirstName: body.FirstName,
+			LastName:  body.LastName,
+			IP:        c.ClientIP(),
+			Method:    c.Request.Method,
+			Path:      c.FullPath(),
+			Status:    c.Writer.Status(),
+			Success:   c.Writer.Status() < http.StatusBadRequest,
+			SignIn:    c.GetBool("signIn"),
+			UserAgent: c.GetHeader("User-Agent"),
+			Referer:   c.GetHeader("Referer"),
+			RequestID: c.GetString("requestId"),
+			ExpiresAt: now.Add(cfg.Retention),
+		}
+		if oid, ok := currentUserID(c); ok {
+			event.UserID = oid
+		}
+
+		q.Enqueue(event)
+	}
+}
+
+// ListLoginEvents queries the access log, newest first. Filters: userId,
+// username, ip, country, success, and from/to as RFC3339 times. Paged
+// with limit and cursor like GetPlayers. Admin only.
+func ListLoginEvents(db *mongo.Database) gin.HandlerFunc {
+	sort := bson.D{{Key: "time", Value: -1}, {Key: "_id", Value: -1}}
+
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		// 1️⃣ Filters
+		filter := bson.M{}
+		if v := c.Query("userId"); v != "" {
+			oid, err := primitive.ObjectIDFromHex(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid userId"})
+				return
+			}
+			filter["userId"] = oid
+		}
+		if v := c.Query("username"); v != "" {
+			filter["username"] = models.NormalizeHandle(v)
+		}
+		if v := c.Query("ip"); v != "" {
+			filter["ip"] = strings.TrimSpace(v)
+		}
+		if v := c.Query("country"); v != "" {
+			filter["country"] = strings.ToUpper(strings.TrimSpace(v))
+		}
+		switch c.Query("success") {
+		case "true":
+			filter["success"] = true
+		case "false":
+			filter["success"] = false
+		}
+
+		timeRange := bson.M{}
+		for param, op := range map[string]string{"from": "$gte", "to": "$lt"} {
+			v := c.Query(param)
+			if v == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + ", use RFC3339"})
+				return
+			}
+			timeRange[op] = t
+		}
+		if len(timeRange) > 0 {
+			filter["time"] = timeRange
+		}
+
+		// 2️⃣ Page
+		limit, err := pageSize(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if raw := c.Query("cursor"); raw != "" {
+			after, err := decodeCursor(raw, sort)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			filter = bson.M{"$and": bson.A{filter, afterCursor(sort, after)}}
+		}
+
+		cur, err := db.Collection("login_events").Find(ctx, filter,
+			options.Find().SetSort(sort).SetLimit(limit+1),
+		)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		defer cur.Close(ctx)
+
+		events := []models.LoginEvent{}
+		if err := cur.All(ctx, &events); err != nil {
+			internalError(c, "decode error", err)
+			return
+		}
+
+		var next string
+		if int64(len(events)) > limit {
+			events = events[:limit]
+			last := events[len(events)-1]
+			next = encodeCursor(bson.D{{Key: "time", Value: last.Time}, {Key: "_id", Value: last.ID}})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"events":     events,
+			"nextCursor": next,
+		})
+	}
+}
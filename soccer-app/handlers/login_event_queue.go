@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"soccer-app/config"
+	"soccer-app/geo"
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// geoLookupTimeout bounds each enrichment so a slow provider can't stall
+// a worker for long.
+const geoLookupTimeout = 3 * time.Second
+
+// LoginEventQueue enriches login events with geo data and writes them out
+// on a fixed pool of workers, so requests never wait on either. When the
+// queue is full new events are dropped and counted rather than blocking.
+type LoginEventQueue struct {
+	db     *mongo.Database
+	geo    geo.Provider
+	events chan models.LoginEvent
+	hooks  []func(context.Context, models.LoginEvent)
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex // guards closed against sends on a closed channel
+	closed bool
+
+	enqueued  atomic.Int64
+	dropped   atomic.Int64
+	written   atomic.Int64
+	failed    atomic.Int64
+	geoErrors atomic.Int64
+}
+
+// LoginEventStats is a snapshot of the queue's counters.
+type LoginEventStats struct {
+	Queued    int   `json:"queued"`
+	Capacity  int   `json:"capacity"`
+	Enqueued  int64 `json:"enqueued"`
+	Dropped   int64 `json:"dropped"`
+	Written   int64 `json:"written"`
+	Failed    int64 `json:"failed"`
+	GeoErrors int64 `json:"geoErrors"`
+}
+
+// NewLoginEventQueue starts cfg.Workers workers. hooks run on a worker
+// after each event is stored.
+func NewLoginEventQueue(db *mongo.Database, cfg config.AccessLog, provider geo.Provider, hooks ...func(context.Context, models.LoginEvent)) *LoginEventQueue {
+	q := &LoginEventQueue{
+		db:     db,
+		geo:    provider,
+		events: make(chan models.LoginEvent, cfg.QueueSize),
+		hooks:  hooks,
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue hands an event to the workers without blocking. It reports
+// false if the event was dropped because the queue is full or closed.
+func (q *LoginEventQueue) Enqueue(e models.LoginEvent) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		q.dropped.Add(1)
+		return false
+	}
+	select {
+	case q.events <- e:
+		q.enqueued.Add(1)
+		return true
+	default:
+		q.dropped.Add(1)
+		return false
+	}
+}
+
+// Close stops accepting events and waits for queued ones to be written,
+// or for ctx to end.
+func (q *LoginEventQueue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.events)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		slog.Warn("login events left unwritten at shutdown", "count", len(q.events))
+		return ctx.Err()
+	}
+}
+
+func (q *LoginEventQueue) Stats() LoginEventStats {
+	return LoginEventStats{
+		Queued:    len(q.events),
+		Capacity:  cap(q.events),
+		Enqueued:  q.enqueued.Load(),
+		Dropped:   q.dropped.Load(),
+		Written:   q.written.Load(),
+		Failed:    q.failed.Load(),
+		GeoErrors: q.geoErrors.Load(),
+	}
+}
+
+func (q *LoginEventQueue) work() {
+	defer q.wg.Done()
+	for e := range q.events {
+		q.process(e)
+	}
+}
+
+func (q *LoginEventQueue) process(e models.LoginEvent) {
+	// Geo only for public IPs
+	if geo.IsPublic(e.IP) {
+		ctx, cancel := context.WithTimeout(context.Background(), geoLookupTimeout)
+		info, err := q.geo.Lookup(ctx, e.IP)
+		cancel()
+		if err != nil {
+			q.geoErrors.Add(1)
+			slog.Debug("geo lookup failed", "ip", e.IP, "requestId", e.RequestID, "error", err)
+		} else {
+			e.Country = info.Country
+			e.City = info.City
+			e.ISP = info.ISP
+			e.Lat = info.Lat
+			e.Lng = info.Lng
+		}
+	}
+
+	res, err := q.db.Collection("login_events").InsertOne(context.Background(), e)
+	if err != nil {
+		q.failed.Add(1)
+		slog.Error("login event write failed", "requestId", e.RequestID, "error", err)
+		return
+	}
+	q.written.Add(1)
+
+	e.ID = res.InsertedID.(primitive.ObjectID)
+	for _, h := range q.hooks {
+		h(context.Background(), e)
+	}
+}
+
+var (
+	loginEventQueuedDesc   = prometheus.NewDesc("login_event_queue_length", "Login events waiting to be written.", nil, nil)
+	loginEventCapacityDesc = prometheus.NewDesc("login_event_queue_capacity", "Login events the queue can hold.", nil, nil)
+	loginEventsDesc        = prometheus.NewDesc("login_events_total", "Login events by what became of them.", []string{"outcome"}, nil)
+	loginEventGeoErrDesc   = prometheus.NewDesc("login_event_geo_errors_total", "Failed geo lookups while enriching login events.", nil, nil)
+)
+
+// Describe and Collect export Stats to Prometheus.
+func (q *LoginEventQueue) Describe(ch chan<- *prometheus.Desc) {
+	ch <- loginEventQueuedDesc
+	ch <- loginEventCapacityDesc
+	ch <- loginEventsDesc
+	ch <- loginEventGeoErrDesc
+}
+
+func (q *LoginEventQueue) Collect(ch chan<- prometheus.Metric) {
+	st := q.Stats()
+	ch <- prometheus.MustNewConstMetric(loginEventQueuedDesc, prometheus.GaugeValue, float64(st.Queued))
+	ch <- prometheus.MustNewConstMetric(loginEventCapacityDesc, prometheus.GaugeValue, float64(st.Capacity))
+	ch <- prometheus.MustNewConstMetric(loginEventsDesc, prometheus.CounterValue, float64(st.Enqueued), "enqueued")
+	ch <- prometheus.MustNewConstMetric(loginEventsDesc, prometheus.CounterValue, float64(st.Dropped), "dropped")
+	ch <- prometheus.MustNewConstMetric(loginEventsDesc, prometheus.CounterValue, float64(st.Written), "written")
+	ch <- prometheus.MustNewConstMetric(loginEventsDesc, prometheus.CounterValue, float64(st.Failed), "failed")
+	ch <- prometheus.MustNewConstMetric(loginEventGeoErrDesc, prometheus.CounterValue, float64(st.GeoErrors))
+}
+
+// LoginEventQueueStats reports the queue's counters. Admin only.
+func LoginEventQueueStats(q *LoginEventQueue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, q.Stats())
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"soccer-app/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecordLoginEventsKeepsBody(t *testing.T) {
+	// No workers: events stay on the queue, nothing touches the database
+	q := NewLoginEventQueue(nil, config.AccessLog{QueueSize: 4}, nil)
+
+	var got int
+	r := gin.New()
+	r.POST("/upload", RecordLoginEvents(q, config.AccessLog{}), func(c *gin.Context) {
+		b, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		got = len(b)
+		c.Status(http.StatusOK)
+	})
+
+	for _, size := range []int{100, maxLoggedBody, maxLoggedBody + 1, 3 * maxLoggedBody} {
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(strings.Repeat("x", size)))
+		req.ContentLength = -1 // as for a chunked upload
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		if got != size {
+			t.Errorf("handler read %d bytes of %d", got, size)
+		}
+	}
+}
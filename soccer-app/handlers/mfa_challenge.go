@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const mfaChallengeTTL = 5 * time.Minute
+
+// newMFAChallenge stores a short-lived challenge for user and returns the
+// raw token the client answers with.
+func newMFAChallenge(ctx context.Context, db *mongo.Database, user models.User) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.Collection("mfa_challenges").InsertOne(ctx, models.MFAChallenge{
+		TokenHash: HashSecret(token),
+		UserID:    user.UserID,
+		ExpiresAt: time.Now().Add(mfaChallengeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CompleteMFAChallenge finishes a sign-in that stopped at the second
+// factor, checking the code the same way LoginUser does. A wrong code
+// leaves the challenge in place so the user can retry until it expires or
+// the account locks.
+func CompleteMFAChallenge(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Challenge    string `json:"challenge"`
+			TOTPCode     string `json:"totpCode"`
+			RecoveryCode string `json:"recoveryCode"`
+		}
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+		if req.Challenge == "" || (req.TOTPCode == "" && req.RecoveryCode == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing fields"})
+			return
+		}
+
+		ctx := context.Background()
+
+		// 1️⃣ Look up the challenge and its user
+		var challenge models.MFAChallenge
+		err := db.Collection("mfa_challenges").FindOne(ctx, bson.M{
+			"tokenHash": HashSecret(req.Challenge),
+			"expiresAt": bson.M{"$gt": time.Now()},
+		}).Decode(&challenge)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "sign-in expired, start again"})
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		var user models.User
+		err = db.Collection("users").FindOne(ctx, bson.M{"_id": challenge.UserID}).Decode(&user)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "sign-in expired, start again"})
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		// ⏳ Same lockout as a password sign-in
+		acctKey := accountKey(user.Username)
+		clientKey := ipKey(c.ClientIP())
+		wait, err := lockedFor(ctx, db, acctKey, clientKey)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if wait > 0 {
+			respondLocked(c, wait)
+			return
+		}
+
+		// 2️⃣ Check the second factor
+		valid, err := verifySecondFactor(ctx, db, user, req.TOTPCode, req.RecoveryCode)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if !valid {
+			if err := recordFailures(ctx, db, acctKey, clientKey); err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			return
+		}
+
+		// 3️⃣ Spend the challenge; a concurrent request that got here first wins
+		res, err := db.Collection("mfa_challenges").DeleteOne(ctx, bson.M{"_id": challenge.ID})
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if res.DeletedCount != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "sign-in expired, start again"})
+			return
+		}
+
+		if err := clearFailures(ctx, db, acctKey); err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		token, err := createSession(ctx, c, db, user, true)
+		if err != nil {
+			respondSessionError(c, err)
+			return
+		}
+		markSignedIn(c, user.UserID)
+
+		role := user.Role
+		if role == "" {
+			role = models.RolePlayer
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"token":     token,
+			"userId":    user.UserID.Hex(),
+			"role":      role,
+			"username":  user.Username,
+			"firstName": user.FirstName,
+			"lastName":  user.LastName,
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCompleteMFAChallenge(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Enrolment always leaves a last step behind, see ConfirmTOTPEnrolment
+	user := insertUser(t, db, models.User{Username: "twofactor", TOTPEnabled: true, TOTPSecret: secret, TOTPLastStep: 1}, "x")
+
+	r := gin.New()
+	r.POST("/auth/mfa/verify", CompleteMFAChallenge(db))
+
+	challenge, err := newMFAChallenge(ctx, db, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := totpAt(secret, time.Now().Unix()/totpPeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	// A wrong code is refused but the challenge survives for a retry
+	w := doJSON(t, r, "POST", "/auth/mfa/verify", "", gin.H{"challenge": challenge, "totpCode": wrong})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong code: status = %d, want 401", w.Code)
+	}
+
+	w = doJSON(t, r, "POST", "/auth/mfa/verify", "", gin.H{"challenge": challenge, "totpCode": code})
+	if w.Code != http.StatusOK {
+		t.Fatalf("right code: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	n, err := db.Collection("sessions").CountDocuments(ctx, bson.M{"userId": user.UserID, "mfa": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("%d MFA sessions, want 1", n)
+	}
+
+	// The challenge is single use
+	w = doJSON(t, r, "POST", "/auth/mfa/verify", "", gin.H{"challenge": challenge, "totpCode": code})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("reused challenge: status = %d, want 401", w.Code)
+	}
+}
@@ -58,7 +58,7 @@ func MovePlayer(db *mongo.Database) gin.HandlerFunc {
 			return
 		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			internalError(c, "db error", err)
 			return
 		}
 
@@ -98,7 +98,7 @@ func MovePlayer(db *mongo.Database) gin.HandlerFunc {
 			},
 		)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update teams"})
+			internalError(c, "failed to update teams", err)
 			return
 		}
 
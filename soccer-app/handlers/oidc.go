@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"soccer-app/config"
+	"soccer-app/models"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/oauth2"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+// oidcStateCookie ties an authorization round trip to the browser that
+// started it, so a callback URL from someone else's flow is useless.
+const oidcStateCookie = "oidc_state"
+
+// OIDCProvider wraps the discovered identity provider and the OAuth2
+// client settings used for the authorization-code flow.
+type OIDCProvider struct {
+	issuer   string
+	verifier *oidc.IDTokenVerifier
+	oauth    oauth2.Config
+}
+
+// NewOIDCProvider runs discovery against the configured issuer.
+func NewOIDCProvider(ctx context.Context, cfg config.OIDC) (*OIDCProvider, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCProvider{
+		issuer:   cfg.Issuer,
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		},
+	}, nil
+}
+
+// authURL stores a fresh state/nonce/PKCE verifier, binds the state to the
+// browser with a cookie and returns the URL to send the browser to.
+// st.LinkUserID is set when linking to an existing account.
+func (p *OIDCProvider) authURL(ctx context.Context, c *gin.Context, db *mongo.Database, st models.OIDCState) (string, error) {
+	state, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	st.State = state
+	st.Nonce = nonce
+	st.CodeVerifier = oauth2.GenerateVerifier()
+	st.ExpiresAt = time.Now().Add(oidcStateTTL)
+
+	if _, err := db.Collection("oidc_states").InsertOne(ctx, st); err != nil {
+		return "", err
+	}
+	p.setStateCookie(c, state, oidcStateTTL)
+
+	return p.oauth.AuthCodeURL(
+		state,
+		oidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(st.CodeVerifier),
+	), nil
+}
+
+// setStateCookie stores state in an HttpOnly cookie scoped to the callback.
+// It is SameSite=Lax because the callback arrives as a top-level redirect
+// from the identity provider; a negative maxAge deletes the cookie.
+func (p *OIDCProvider) setStateCookie(c *gin.Context, state string, maxAge time.Duration) {
+	path := "/"
+	if u, err := url.Parse(p.oauth.RedirectURL); err == nil && u.Path != "" {
+		path = u.Path
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int(maxAge/time.Second), path, "",
+		strings.HasPrefix(p.oauth.RedirectURL, "https://"), true)
+}
+
+// OIDCLogin redirects the browser to the identity provider.
+func OIDCLogin(db *mongo.Database, p *OIDCProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u, err := p.authURL(context.Background(), c, db, models.OIDCState{})
+		if err != nil {
+			internalError(c, "failed to start login", err)
+			return
+		}
+		c.Redirect(http.StatusFound, u)
+	}
+}
+
+// OIDCLink returns an authorization URL that, once completed, links the
+// external identity to the authenticated user.
+func OIDCLink(db *mongo.Database, p *OIDCProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userOID, ok := currentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		u, err := p.authURL(context.Background(), c, db, models.OIDCState{LinkUserID: userOID})
+		if err != nil {
+			internalError(c, "failed to start linking", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"url": u})
+	}
+}
+
+// OIDCCallback finishes the authorization-code flow. It either links the
+// identity (when the flow was started by OIDCLink) or signs in the user the
+// identity is already linked to, then hands the session token to the sign-in
+// page in the URL fragment. Users with TOTP get a challenge for
+// CompleteMFAChallenge instead of a token.
+func OIDCCallback(db *mongo.Database, p *OIDCProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		fail := func(msg string) {
+			c.Redirect(http.StatusFound, "/#error="+url.QueryEscape(msg))
+		}
+
+		if e := c.Query("error"); e != "" {
+			fail(e)
+			return
+		}
+
+		// 1️⃣ The flow must have been started by this browser
+		cookie, err := c.Cookie(oidcStateCookie)
+		p.setStateCookie(c, "", -1)
+		if err != nil || subtle.ConstantTimeCompare([]byte(cookie), []byte(c.Query("state"))) != 1 {
+			fail("sign-in was started in another browser, try again")
+			return
+		}
+
+		// 2️⃣ Consume state (single use)
+		var st models.OIDCState
+		err = db.Collection("oidc_states").FindOneAndDelete(ctx, bson.M{
+			"_id":       c.Query("state"),
+			"expiresAt": bson.M{"$gt": time.Now()},
+		}).Decode(&st)
+		if err == mongo.ErrNoDocuments {
+			fail("login expired, try again")
+			return
+		}
+		if err != nil {
+			fail("db error")
+			return
+		}
+
+		// 3️⃣ Exchange code and verify the ID token
+		tok, err := p.oauth.Exchange(ctx, c.Query("code"), oauth2.VerifierOption(st.CodeVerifier))
+		if err != nil {
+			fail("code exchange failed")
+			return
+		}
+		rawID, ok := tok.Extra("id_token").(string)
+		if !ok {
+			fail("no id_token in response")
+			return
+		}
+		idToken, err := p.verifier.Verify(ctx, rawID)
+		if err != nil {
+			fail("invalid id_token")
+			return
+		}
+		if idToken.Nonce != st.Nonce {
+			fail("invalid nonce")
+			return
+		}
+
+		var claims struct {
+			Email string `json:"email"`
+		}
+		_ = idToken.Claims(&claims)
+
+		identity := bson.M{
+			"identities.issuer":  p.issuer,
+			"identities.subject": idToken.Subject,
+		}
+
+		// 4️⃣ Linking: attach identity to the user who started the flow
+		if !st.LinkUserID.IsZero() {
+			_, err := db.Collection("users").UpdateOne(
+				ctx,
+				bson.M{
+					"_id":        st.LinkUserID,
+					"identities": bson.M{"$not": bson.M{"$elemMatch": bson.M{"issuer": p.issuer, "subject": idToken.Subject}}},
+				},
+				bson.M{"$push": bson.M{"identities": models.Identity{
+					Issuer:   p.issuer,
+					Subject:  idToken.Subject,
+					Email:    claims.Email,
+					LinkedAt: time.Now(),
+				}}},
+			)
+			if mongo.IsDuplicateKeyError(err) {
+				fail("this account is already linked to another user")
+				return
+			}
+			if err != nil {
+				fail("db error")
+				return
+			}
+			writeAudit(ctx, db, "oidc.linked", st.LinkUserID, st.LinkUserID, c.ClientIP(), p.issuer)
+		}
+
+		// 5️⃣ Find the linked user and open a session
+		var user models.User
+		err = db.Collection("users").FindOne(ctx, identity).Decode(&user)
+		if err == mongo.ErrNoDocuments {
+			fail("no account is linked to this identity, sign in and link it first")
+			return
+		}
+		if err != nil {
+			fail("db error")
+			return
+		}
+
+		// 6️⃣ TOTP-enrolled users still owe their second factor
+		if user.TOTPEnabled {
+			challenge, err := newMFAChallenge(ctx, db, user)
+			if err != nil {
+				fail("db error")
+				return
+			}
+			c.Redirect(http.StatusFound, "/#totpChallenge="+url.QueryEscape(challenge))
+			return
+		}
+
+		token, err := createSession(ctx, c, db, user, false)
+		if errors.Is(err, errReverifyRequired) || errors.Is(err, errAccountDeleted) {
+			fail(err.Error())
+			return
+		}
+		if err != nil {
+			fail("failed to create session")
+			return
+		}
+		markSignedIn(c, user.UserID)
+
+		c.Redirect(http.StatusFound, "/#token="+url.QueryEscape(token))
+	}
+}
+
+// UnlinkOIDC removes an external identity from the authenticated user,
+// unless it is the only way left to sign in.
+func UnlinkOIDC(db *mongo.Database, p *OIDCProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		userOID, ok := currentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		// Another secret, passkey or identity must remain; checked in the
+		// same update as the pull
+		res, err := db.Collection("users").UpdateOne(ctx,
+			bson.M{
+				"_id":               userOID,
+				"identities.issuer": p.issuer,
+				"$or": bson.A{
+					bson.M{"secretHash": bson.M{"$exists": true, "$ne": ""}},
+					bson.M{"passkeys.0": bson.M{"$exists": true}},
+					bson.M{"identities": bson.M{"$elemMatch": bson.M{"issuer": bson.M{"$ne": p.issuer}}}},
+				},
+			},
+			bson.M{"$pull": bson.M{"identities": bson.M{"issuer": p.issuer}}},
+		)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if res.MatchedCount == 0 {
+			linked, err := db.Collection("users").CountDocuments(ctx, bson.M{"_id": userOID, "identities.issuer": p.issuer})
+			if err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+			if linked > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "this is your only sign-in method; set a secret or add a passkey first"})
+				return
+			}
+		}
+
+		if res.ModifiedCount > 0 {
+			writeAudit(ctx, db, "oidc.unlinked", userOID, userOID, c.ClientIP(), p.issuer)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
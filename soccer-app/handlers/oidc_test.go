@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"soccer-app/config"
+	"soccer-app/devidp"
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	testClientID     = "soccer-app"
+	testClientSecret = "secret"
+)
+
+// testIdP runs a devidp provider on a local test server. Tests skip the
+// authorize page and mint codes with issue.
+type testIdP struct {
+	*devidp.Provider
+	srv *httptest.Server
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	p, err := devidp.New("", testClientID, testClientSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(p.Handler())
+	t.Cleanup(srv.Close)
+	p.Issuer = srv.URL
+	return &testIdP{Provider: p, srv: srv}
+}
+
+// issue plays the authorize step for authURL and returns the code and
+// state the browser would bring back. edit may tamper with the grant.
+func (p *testIdP) issue(t *testing.T, authURL, subject string, edit func(*devidp.Grant)) (code, state string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("code_challenge_method = %q, want S256", q.Get("code_challenge_method"))
+	}
+
+	g := devidp.Grant{
+		ClientID:      q.Get("client_id"),
+		RedirectURI:   q.Get("redirect_uri"),
+		Nonce:         q.Get("nonce"),
+		Subject:       subject,
+		CodeChallenge: q.Get("code_challenge"),
+	}
+	if edit != nil {
+		edit(&g)
+	}
+	return p.Issue(g), q.Get("state")
+}
+
+// oidcRouter wires the OIDC handlers against a provider discovered from idp.
+func oidcRouter(t *testing.T, db *mongo.Database, idp *testIdP) *gin.Engine {
+	t.Helper()
+	p, err := NewOIDCProvider(context.Background(), config.OIDC{
+		Issuer:       idp.srv.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  "http://app.test/auth/oidc/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/auth/oidc/login", OIDCLogin(db, p))
+	r.GET("/auth/oidc/callback", OIDCCallback(db, p))
+	r.POST("/auth/oidc/link", AuthRequired(db), OIDCLink(db, p))
+	r.DELETE("/auth/oidc/link", AuthRequired(db), UnlinkOIDC(db, p))
+	return r
+}
+
+// stateCookie returns the state cookie a response set.
+func stateCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, ck := range w.Result().Cookies() {
+		if ck.Name == oidcStateCookie {
+			if !ck.HttpOnly || ck.SameSite != http.SameSiteLaxMode {
+				t.Fatalf("state cookie is not HttpOnly/SameSite=Lax: %+v", ck)
+			}
+			return ck
+		}
+	}
+	t.Fatal("no state cookie set")
+	return nil
+}
+
+// startLogin begins a plain sign-in and returns the IdP URL and cookie.
+func startLogin(t *testing.T, r http.Handler) (string, *http.Cookie) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/auth/oidc/login", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("login: status = %d, want 302", w.Code)
+	}
+	return w.Header().Get("Location"), stateCookie(t, w)
+}
+
+// callback brings the browser back from the IdP and returns where the
+// app sent it next.
+func callback(t *testing.T, r http.Handler, code, state string, cookie *http.Cookie) string {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/auth/oidc/callback?"+url.Values{"code": {code}, "state": {state}}.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("callback: status = %d, want 302", w.Code)
+	}
+	return w.Header().Get("Location")
+}
+
+func TestOIDCLinkThenLogin(t *testing.T) {
+	db := testDB(t)
+	idp := newTestIdP(t)
+	r := oidcRouter(t, db, idp)
+	alice := insertUser(t, db, models.User{Username: "alice"}, "x")
+
+	// Link: the signed-in user starts the flow and completes it
+	w := doJSON(t, r, "POST", "/auth/oidc/link", insertSession(t, db, alice), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("link: status = %d, want 200", w.Code)
+	}
+	var body struct{ URL string }
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	code, state := idp.issue(t, body.URL, "alice-sub", nil)
+	if loc := callback(t, r, code, state, stateCookie(t, w)); !strings.HasPrefix(loc, "/#token=") {
+		t.Fatalf("link callback went to %q", loc)
+	}
+
+	n, err := db.Collection("users").CountDocuments(context.Background(), bson.M{
+		"_id":                alice.UserID,
+		"identities.issuer":  idp.srv.URL,
+		"identities.subject": "alice-sub",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatal("identity was not linked")
+	}
+
+	// Login: the linked identity now signs alice in
+	authURL, cookie := startLogin(t, r)
+	code, state = idp.issue(t, authURL, "alice-sub", nil)
+	if loc := callback(t, r, code, state, cookie); !strings.HasPrefix(loc, "/#token=") {
+		t.Fatalf("login callback went to %q", loc)
+	}
+}
+
+func TestOIDCCallbackRejects(t *testing.T) {
+	db := testDB(t)
+	idp := newTestIdP(t)
+	r := oidcRouter(t, db, idp)
+	insertUser(t, db, models.User{Username: "bob", Identities: []models.Identity{{
+		Issuer:  idp.srv.URL,
+		Subject: "bob-sub",
+	}}}, "x")
+
+	cases := []struct {
+		name   string
+		edit   func(*devidp.Grant)
+		cookie func(own *http.Cookie) *http.Cookie
+		want   string
+	}{
+		{
+			name:   "no state cookie",
+			cookie: func(*http.Cookie) *http.Cookie { return nil },
+			want:   "started in another browser",
+		},
+		{
+			name: "state cookie from another flow",
+			cookie: func(*http.Cookie) *http.Cookie {
+				_, other := startLogin(t, r)
+				return other
+			},
+			want: "started in another browser",
+		},
+		{
+			name: "bad nonce",
+			edit: func(g *devidp.Grant) { g.Nonce = "someone-elses-nonce" },
+			want: "invalid nonce",
+		},
+		{
+			name: "wrong issuer",
+			edit: func(g *devidp.Grant) { g.Issuer = "https://evil.example" },
+			want: "invalid id_token",
+		},
+		{
+			name: "PKCE verifier mismatch",
+			edit: func(g *devidp.Grant) { g.CodeChallenge = "not-the-challenge" },
+			want: "code exchange failed",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			authURL, cookie := startLogin(t, r)
+			if tc.cookie != nil {
+				cookie = tc.cookie(cookie)
+			}
+			code, state := idp.issue(t, authURL, "bob-sub", tc.edit)
+
+			loc := callback(t, r, code, state, cookie)
+			if !strings.HasPrefix(loc, "/#error=") {
+				t.Fatalf("callback went to %q, want an error", loc)
+			}
+			if msg, _ := url.QueryUnescape(strings.TrimPrefix(loc, "/#error=")); !strings.Contains(msg, tc.want) {
+				t.Fatalf("error = %q, want it to mention %q", msg, tc.want)
+			}
+		})
+	}
+}
+
+func TestUnlinkOIDCKeepsASignInMethod(t *testing.T) {
+	db := testDB(t)
+	idp := newTestIdP(t)
+	r := oidcRouter(t, db, idp)
+	identity := []models.Identity{{Issuer: idp.srv.URL, Subject: "sub"}}
+
+	// Only an identity: the user would be locked out
+	only := insertUser(t, db, models.User{Username: "oidc-only", Identities: identity}, "")
+	if _, err := db.Collection("users").UpdateOne(context.Background(),
+		bson.M{"_id": only.UserID}, bson.M{"$unset": bson.M{"secretHash": ""}},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if w := doJSON(t, r, "DELETE", "/auth/oidc/link", insertSession(t, db, only), nil); w.Code != http.StatusConflict {
+		t.Fatalf("only method: status = %d, want 409: %s", w.Code, w.Body)
+	}
+
+	withSecret := insertUser(t, db, models.User{Username: "both", Identities: identity}, "x")
+	if w := doJSON(t, r, "DELETE", "/auth/oidc/link", insertSession(t, db, withSecret), nil); w.Code != http.StatusOK {
+		t.Fatalf("secret left: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	n, err := db.Collection("users").CountDocuments(context.Background(), bson.M{"_id": withSecret.UserID, "identities.issuer": idp.srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatal("identity was not unlinked")
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+var errBadCursor = errors.New("invalid cursor")
+
+// pageSize reads ?limit=, defaulting to defaultPageSize.
+func pageSize(c *gin.Context) (int64, error) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return defaultPageSize, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > maxPageSize {
+		return 0, errors.New("limit must be between 1 and " + strconv.Itoa(maxPageSize))
+	}
+	return int64(n), nil
+}
+
+// encodeCursor packs the sort-key values of the last document on a page
+// into an opaque token. bson keeps the values' types intact.
+func encodeCursor(values bson.D) string {
+	b, err := bson.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string, sort bson.D) (bson.D, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errBadCursor
+	}
+	var values bson.D
+	if err := bson.Unmarshal(b, &values); err != nil || len(values) != len(sort) {
+		return nil, errBadCursor
+	}
+	for i := range sort {
+		if values[i].Key != sort[i].Key {
+			return nil, errBadCursor
+		}
+	}
+	return values, nil
+}
+
+// afterCursor returns a filter matching documents that sort after values
+// under sort. The last sort key must be unique (normally _id).
+func afterCursor(sort, values bson.D) bson.M {
+	or := make(bson.A, 0, len(sort))
+	for i, s := range sort {
+		cond := bson.M{}
+		for _, v := range values[:i] {
+			cond[v.Key] = v.Value
+		}
+		op := "$gt"
+		if s.Value == -1 {
+			op = "$lt"
+		}
+		cond[s.Key] = bson.M{op: values[i].Value}
+		or = append(or, cond)
+	}
+	return bson.M{"$or": or}
+}
@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"soccer-app/config"
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const webauthnSessionTTL = 5 * time.Minute
+
+// passkeyUser adapts models.User to the webauthn.User interface.
+type passkeyUser struct {
+	models.User
+}
+
+func (u passkeyUser) WebAuthnID() []byte {
+	id := u.UserID
+	return id[:]
+}
+
+func (u passkeyUser) WebAuthnName() string {
+	return u.Username
+}
+
+func (u passkeyUser) WebAuthnDisplayName() string {
+	return u.FirstName + " " + u.LastName
+}
+
+func (u passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	creds := make([]webauthn.Credential, 0, len(u.Passkeys))
+	for _, p := range u.Passkeys {
+		creds = append(creds, p.Credential)
+	}
+	return creds
+}
+
+// NewWebAuthn builds the relying party from config.
+func NewWebAuthn(cfg config.WebAuthn) (*webauthn.WebAuthn, error) {
+	return webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+	})
+}
+
+// saveCeremony stores session data for the second half of a ceremony and
+// returns the ID the client must send back.
+func saveCeremony(ctx context.Context, db *mongo.Database, userID primitive.ObjectID, sd *webauthn.SessionData) (string, error) {
+	id, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(sd)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.Collection("webauthn_sessions").InsertOne(ctx, models.WebAuthnSession{
+		ID:        id,
+		UserID:    userID,
+		Data:      data,
+		ExpiresAt: time.Now().Add(webauthnSessionTTL),
+	})
+	return id, err
+}
+
+// takeCeremony loads and deletes ceremony session data (single use).
+func takeCeremony(ctx context.Context, db *mongo.Database, id string, userID primitive.ObjectID) (webauthn.SessionData, error) {
+	var sd webauthn.SessionData
+
+	filter := bson.M{"_id": id, "expiresAt": bson.M{"$gt": time.Now()}}
+	if !userID.IsZero() {
+		filter["userId"] = userID
+	}
+
+	var ws models.WebAuthnSession
+	if err := db.Collection("webauthn_sessions").FindOneAndDelete(ctx, filter).Decode(&ws); err != nil {
+		return sd, err
+	}
+	err := json.Unmarshal(ws.Data, &sd)
+	return sd, err
+}
+
+// BeginPasskeyRegistration starts registering a passkey for the
+// authenticated user.
+func BeginPasskeyRegistration(db *mongo.Database, wa *webauthn.WebAuthn) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := loadCurrentUser(c, db)
+		if !ok {
+			return
+		}
+		pu := passkeyUser{user}
+
+		creation, sd, err := wa.BeginRegistration(
+			pu,
+			webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+			webauthn.WithExclusions(webauthn.Credentials(pu.WebAuthnCredentials()).CredentialDescriptors()),
+		)
+		if err != nil {
+			internalError(c, "failed to start registration", err)
+			return
+		}
+
+		id, err := saveCeremony(context.Background(), db, user.UserID, sd)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"sessionId": id, "options": creation})
+	}
+}
+
+// FinishPasskeyRegistration verifies the authenticator's attestation and
+// stores the new credential. The request body is the
+// PublicKeyCredential JSON from navigator.credentials.create().
+func FinishPasskeyRegistration(db *mongo.Database, wa *webauthn.WebAuthn) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		user, ok := loadCurrentUser(c, db)
+		if !ok {
+			return
+		}
+
+		sd, err := takeCeremony(ctx, db, c.Query("sessionId"), user.UserID)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "registration expired, start again"})
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		cred, err := wa.FinishRegistration(passkeyUser{user}, sd, c.Request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "passkey verification failed"})
+			return
+		}
+
+		name := c.Query("name")
+		if name == "" {
+			name = "Passkey " + time.Now().Format("2006-01-02")
+		}
+
+		_, err = db.Collection("users").UpdateOne(
+			ctx,
+			bson.M{"_id": user.UserID},
+			bson.M{"$push": bson.M{"passkeys": models.Passkey{
+				Name:       name,
+				Credential: *cred,
+				CreatedAt:  time.Now(),
+			}}},
+		)
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "passkey already registered"})
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		writeAudit(ctx, db, "passkey.registered", user.UserID, user.UserID, c.ClientIP(), name)
+
+		c.JSON(http.StatusCreated, gin.H{
+			"id":   base64.RawURLEncoding.EncodeToString(cred.ID),
+			"name": name,
+		})
+	}
+}
+
+// ListPasskeys returns the authenticated user's passkeys.
+func ListPasskeys(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := loadCurrentUser(c, db)
+		if !ok {
+			return
+		}
+
+		out := make([]gin.H, 0, len(user.Passkeys))
+		for _, p := range user.Passkeys {
+			out = append(out, gin.H{
+				"id":         base64.RawURLEncoding.EncodeToString(p.Credential.ID),
+				"name":       p.Name,
+				"createdAt":  p.CreatedAt,
+				"lastUsedAt": p.LastUsedAt,
+			})
+		}
+
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// DeletePasskey removes one of the authenticated user's passkeys.
+// :id is the base64url credential ID returned by ListPasskeys.
+func DeletePasskey(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		userOID, ok := currentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		credID, err := base64.RawURLEncoding.DecodeString(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid passkey id"})
+			return
+		}
+
+		res, err := db.Collection("users").UpdateOne(
+			ctx,
+			bson.M{"_id": userOID},
+			bson.M{"$pull": bson.M{"passkeys": bson.M{"credential.id": credID}}},
+		)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if res.ModifiedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "passkey not found"})
+			return
+		}
+
+		writeAudit(ctx, db, "passkey.deleted", userOID, userOID, c.ClientIP(), c.Param("id"))
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// BeginPasskeyLogin starts a discoverable-credential login; the browser
+// picks the passkey so no username is needed.
+func BeginPasskeyLogin(db *mongo.Database, wa *webauthn.WebAuthn) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		assertion, sd, err := wa.BeginDiscoverableLogin()
+		if err != nil {
+			internalError(c, "failed to start login", err)
+			return
+		}
+
+		id, err := saveCeremony(context.Background(), db, primitive.NilObjectID, sd)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"sessionId": id, "options": assertion})
+	}
+}
+
+// FinishPasskeyLogin verifies the assertion from navigator.credentials.get()
+// and opens a session for the passkey's owner. TOTP-enrolled owners whose
+// authenticator did not verify them get an MFA challenge instead, answered
+// at /auth/mfa/verify.
+func FinishPasskeyLogin(db *mongo.Database, wa *webauthn.WebAuthn) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		sd, err := takeCeremony(ctx, db, c.Query("sessionId"), primitive.NilObjectID)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "login expired, start again"})
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		// The user handle is the 12-byte ObjectID set at registration
+		var owner models.User
+		findOwner := func(rawID, userHandle []byte) (webauthn.User, error) {
+			if len(userHandle) != len(primitive.ObjectID{}) {
+				return nil, protocol.ErrBadRequest.WithDetails("unknown user handle")
+			}
+			var oid primitive.ObjectID
+			copy(oid[:], userHandle)
+
+			if err := db.Collection("users").FindOne(ctx, bson.M{"_id": oid}).Decode(&owner); err != nil {
+				return nil, err
+			}
+			return passkeyUser{owner}, nil
+		}
+
+		cred, err := wa.FinishDiscoverableLogin(findOwner, sd, c.Request)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			return
+		}
+
+		// A sign count that didn't go up may mean the authenticator was cloned
+		if cred.Authenticator.CloneWarning {
+			writeAudit(ctx, db, "passkey.clone_warning", owner.UserID, owner.UserID, c.ClientIP(), "")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			return
+		}
+
+		if _, err := db.Collection("users").UpdateOne(
+			ctx,
+			bson.M{"_id": owner.UserID, "passkeys.credential.id": cred.ID},
+			bson.M{"$set": bson.M{
+				"passkeys.$.credential.authenticator.signcount": cred.Authenticator.SignCount,
+				"passkeys.$.lastUsedAt":                         time.Now(),
+			}},
+		); err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		// 🔑 A passkey that verified the user (PIN or biometric) is a second
+		// factor in itself; otherwise TOTP-enrolled users still owe theirs
+		mfa := cred.Flags.UserVerified
+		if owner.TOTPEnabled && !mfa {
+			challenge, err := newMFAChallenge(ctx, db, owner)
+			if err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":        "two-factor code required",
+				"totpRequired": true,
+				"challenge":    challenge,
+			})
+			return
+		}
+
+		token, err := createSession(ctx, c, db, owner, mfa)
+		if err != nil {
+			respondSessionError(c, err)
+			return
+		}
+		markSignedIn(c, owner.UserID)
+
+		role := owner.Role
+		if role == "" {
+			role = models.RolePlayer
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"token":     token,
+			"userId":    owner.UserID.Hex(),
+			"role":      role,
+			"username":  owner.Username,
+			"firstName": owner.FirstName,
+			"lastName":  owner.LastName,
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"soccer-app/config"
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	testRPID   = "app.test"
+	testOrigin = "https://app.test"
+)
+
+var b64u = base64.RawURLEncoding
+
+// softAuthenticator is a software passkey: an ES256 key pair with a
+// sign counter, producing the JSON a browser would post.
+type softAuthenticator struct {
+	key        *ecdsa.PrivateKey
+	credID     []byte
+	userHandle []byte
+	counter    uint32
+	// unverified leaves out the user-verified flag, like a security key
+	// without a PIN
+	unverified bool
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{key: key, credID: id}
+}
+
+// clientData builds clientDataJSON for a ceremony of type typ.
+func (a *softAuthenticator) clientData(t *testing.T, typ, challenge string) []byte {
+	t.Helper()
+	b, err := json.Marshal(map[string]any{
+		"type":      typ,
+		"challenge": challenge,
+		"origin":    testOrigin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// authData builds authenticator data with user present and, unless
+// unverified, user verified set; attested adds the credential ID and
+// public key.
+func (a *softAuthenticator) authData(t *testing.T, attested bool) []byte {
+	t.Helper()
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	flags := byte(0x05)
+	if a.unverified {
+		flags = 0x01
+	}
+	if attested {
+		flags |= 0x40
+	}
+
+	var buf bytes.Buffer
+	buf.Write(rpIDHash[:])
+	buf.WriteByte(flags)
+	_ = binary.Write(&buf, binary.BigEndian, a.counter)
+	if !attested {
+		return buf.Bytes()
+	}
+
+	buf.Write(make([]byte, 16)) // AAGUID
+	_ = binary.Write(&buf, binary.BigEndian, uint16(len(a.credID)))
+	buf.Write(a.credID)
+	pub, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(pub)
+	return buf.Bytes()
+}
+
+// create answers a registration ceremony's options.
+func (a *softAuthenticator) create(t *testing.T, options ceremonyOptions) gin.H {
+	t.Helper()
+	a.userHandle = options.userID(t)
+	att, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(t, true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return gin.H{
+		"id":    b64u.EncodeToString(a.credID),
+		"rawId": b64u.EncodeToString(a.credID),
+		"type":  "public-key",
+		"response": gin.H{
+			"clientDataJSON":    b64u.EncodeToString(a.clientData(t, "webauthn.create", options.Options.PublicKey.Challenge)),
+			"attestationObject": b64u.EncodeToString(att),
+		},
+	}
+}
+
+// get answers a login ceremony's options, bumping the sign counter.
+func (a *softAuthenticator) get(t *testing.T, options ceremonyOptions) gin.H {
+	t.Helper()
+	a.counter++
+	authData := a.authData(t, false)
+	clientData := a.clientData(t, "webauthn.get", options.Options.PublicKey.Challenge)
+
+	clientHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return gin.H{
+		"id":    b64u.EncodeToString(a.credID),
+		"rawId": b64u.EncodeToString(a.credID),
+		"type":  "public-key",
+		"response": gin.H{
+			"clientDataJSON":    b64u.EncodeToString(clientData),
+			"authenticatorData": b64u.EncodeToString(authData),
+			"signature":         b64u.EncodeToString(sig),
+			"userHandle":        b64u.EncodeToString(a.userHandle),
+		},
+	}
+}
+
+// ceremonyOptions is the part of a begin response the authenticator needs.
+type ceremonyOptions struct {
+	SessionID string `json:"sessionId"`
+	Options   struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+			User      struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		} `json:"publicKey"`
+	} `json:"options"`
+}
+
+func (o ceremonyOptions) userID(t *testing.T) []byte {
+	t.Helper()
+	id, err := b64u.DecodeString(o.Options.PublicKey.User.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func passkeyRouter(t *testing.T, db *mongo.Database) *gin.Engine {
+	t.Helper()
+	wa, err := NewWebAuthn(config.WebAuthn{
+		RPID:          testRPID,
+		RPDisplayName: "Soccer",
+		RPOrigins:     []string{testOrigin},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	auth := AuthRequired(db)
+	r.POST("/passkeys/register/begin", auth, BeginPasskeyRegistration(db, wa))
+	r.POST("/passkeys/register/finish", auth, FinishPasskeyRegistration(db, wa))
+	r.POST("/passkey/login/begin", BeginPasskeyLogin(db, wa))
+	r.POST("/passkey/login/finish", FinishPasskeyLogin(db, wa))
+	return r
+}
+
+// begin starts a ceremony at path and returns its options.
+func begin(t *testing.T, r http.Handler, path, token string) ceremonyOptions {
+	t.Helper()
+	w := doJSON(t, r, "POST", path, token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: status = %d, want 200", path, w.Code)
+	}
+	var o ceremonyOptions
+	if err := json.Unmarshal(w.Body.Bytes(), &o); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+// registerPasskey runs a full registration ceremony for the token's user.
+func registerPasskey(t *testing.T, r http.Handler, token string, a *softAuthenticator) {
+	t.Helper()
+	o := begin(t, r, "/passkeys/register/begin", token)
+	w := doJSON(t, r, "POST", "/passkeys/register/finish?sessionId="+o.SessionID, token, a.create(t, o))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register: status = %d, want 201: %s", w.Code, w.Body)
+	}
+}
+
+func TestPasskeyRegisterAndLogin(t *testing.T) {
+	db := testDB(t)
+	r := passkeyRouter(t, db)
+	alice := insertUser(t, db, models.User{Username: "alice"}, "x")
+	a := newSoftAuthenticator(t)
+
+	registerPasskey(t, r, insertSession(t, db, alice), a)
+
+	o := begin(t, r, "/passkey/login/begin", "")
+	assertion := a.get(t, o)
+	w := doJSON(t, r, "POST", "/passkey/login/finish?sessionId="+o.SessionID, "", assertion)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var body struct{ UserID string }
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.UserID != alice.UserID.Hex() {
+		t.Fatalf("signed in as %s, want alice", body.UserID)
+	}
+
+	// The ceremony is single use
+	w = doJSON(t, r, "POST", "/passkey/login/finish?sessionId="+o.SessionID, "", assertion)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("reused ceremony: status = %d, want 400", w.Code)
+	}
+
+	// A captured assertion does not answer a fresh challenge
+	o = begin(t, r, "/passkey/login/begin", "")
+	w = doJSON(t, r, "POST", "/passkey/login/finish?sessionId="+o.SessionID, "", assertion)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed assertion: status = %d, want 401", w.Code)
+	}
+}
+
+func TestPasskeyLoginRejectsStaleSignCount(t *testing.T) {
+	db := testDB(t)
+	r := passkeyRouter(t, db)
+	alice := insertUser(t, db, models.User{Username: "alice"}, "x")
+	a := newSoftAuthenticator(t)
+	registerPasskey(t, r, insertSession(t, db, alice), a)
+
+	o := begin(t, r, "/passkey/login/begin", "")
+	if w := doJSON(t, r, "POST", "/passkey/login/finish?sessionId="+o.SessionID, "", a.get(t, o)); w.Code != http.StatusOK {
+		t.Fatalf("first login: status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	// A clone of the authenticator still has the old counter
+	a.counter--
+	o = begin(t, r, "/passkey/login/begin", "")
+	if w := doJSON(t, r, "POST", "/passkey/login/finish?sessionId="+o.SessionID, "", a.get(t, o)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("stale sign count: status = %d, want 401", w.Code)
+	}
+
+	n, err := db.Collection("audit_log").CountDocuments(context.Background(), bson.M{"action": "passkey.clone_warning"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("%d clone warnings audited, want 1", n)
+	}
+}
+
+func TestPasskeyOfAnotherUser(t *testing.T) {
+	db := testDB(t)
+	r := passkeyRouter(t, db)
+	alice := insertUser(t, db, models.User{Username: "alice"}, "x")
+	bob := insertUser(t, db, models.User{Username: "bob"}, "y")
+	a := newSoftAuthenticator(t)
+	registerPasskey(t, r, insertSession(t, db, alice), a)
+
+	// Alice's credential presented as bob's
+	a.userHandle = bob.UserID[:]
+	o := begin(t, r, "/passkey/login/begin", "")
+	if w := doJSON(t, r, "POST", "/passkey/login/finish?sessionId="+o.SessionID, "", a.get(t, o)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("credential of another user: status = %d, want 401", w.Code)
+	}
+
+	// Bob can't finish a registration ceremony alice started
+	o = begin(t, r, "/passkeys/register/begin", insertSession(t, db, alice))
+	other := newSoftAuthenticator(t)
+	w := doJSON(t, r, "POST", "/passkeys/register/finish?sessionId="+o.SessionID, insertSession(t, db, bob), other.create(t, o))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("registration ceremony of another user: status = %d, want 400", w.Code)
+	}
+}
+
+func TestPasskeyLoginSecondFactor(t *testing.T) {
+	db := testDB(t)
+	r := passkeyRouter(t, db)
+	tina := insertUser(t, db, models.User{Username: "tina", TOTPEnabled: true, TOTPSecret: "JBSWY3DPEHPK3PXP"}, "x")
+	a := newSoftAuthenticator(t)
+	registerPasskey(t, r, insertSession(t, db, tina), a)
+
+	// A verified passkey counts as both factors
+	o := begin(t, r, "/passkey/login/begin", "")
+	if w := doJSON(t, r, "POST", "/passkey/login/finish?sessionId="+o.SessionID, "", a.get(t, o)); w.Code != http.StatusOK {
+		t.Fatalf("verified: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	n, err := db.Collection("sessions").CountDocuments(context.Background(), bson.M{"userId": tina.UserID, "mfa": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("%d MFA sessions, want 1", n)
+	}
+
+	// Presence alone still owes the TOTP code
+	a.unverified = true
+	o = begin(t, r, "/passkey/login/begin", "")
+	w := doJSON(t, r, "POST", "/passkey/login/finish?sessionId="+o.SessionID, "", a.get(t, o))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unverified: status = %d, want 401: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Token     string `json:"token"`
+		Challenge string `json:"challenge"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Token != "" || body.Challenge == "" {
+		t.Fatalf("unverified: want a challenge and no token: %s", w.Body)
+	}
+}
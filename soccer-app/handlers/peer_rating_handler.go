@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SubmitPeerRatings records the authenticated player's ratings of the
+// other players from a match, once the poll has closed. Players only rate
+// their own teammates. Rating the same player again for the same match replaces the old rating.
+func SubmitPeerRatings(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		pollOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid poll id"})
+			return
+		}
+
+		raterOID, ok := currentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		var req struct {
+			Ratings []struct {
+				UserID primitive.ObjectID `json:"userId"`
+				Skills []models.Skill     `json:"skills"`
+			} `json:"ratings"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.Ratings) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		// 1️⃣ Ratings open once the match has been played
+		var poll models.Poll
+		err = db.Collection("polls").FindOne(ctx, bson.M{"_id": pollOID}).Decode(&poll)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "poll not found"})
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if time.Now().Before(poll.EndsAt) {
+			c.JSON(http.StatusConflict, gin.H{"error": "ratings open after the match"})
+			return
+		}
+
+		// 2️⃣ Who played in this match
+		var teams models.PollTeams
+		err = db.Collection("teams").FindOne(ctx, bson.M{"pollId": pollOID}).Decode(&teams)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no teams for this poll"})
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		team := map[primitive.ObjectID]string{}
+		for _, u := range teams.TeamA {
+			team[u.UserID] = "A"
+		}
+		for _, u := range teams.TeamB {
+			team[u.UserID] = "B"
+		}
+		raterTeam, played := team[raterOID]
+		if !played {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only players from this match can rate"})
+			return
+		}
+
+		// 3️⃣ Validate every rating before storing any
+		rateeIDs := make([]primitive.ObjectID, 0, len(req.Ratings))
+		for _, r := range req.Ratings {
+			if r.UserID == raterOID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "you cannot rate yourself"})
+				return
+			}
+			rateeTeam, ok := team[r.UserID]
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "player " + r.UserID.Hex() + " was not in this match"})
+				return
+			}
+			if rateeTeam != raterTeam {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "player " + r.UserID.Hex() + " was not on your team"})
+				return
+			}
+			rateeIDs = append(rateeIDs, r.UserID)
+		}
+
+		cur, err := db.Collection("users").Find(ctx, bson.M{"_id": bson.M{"$in": rateeIDs}})
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		var ratees []models.User
+		if err := cur.All(ctx, &ratees); err != nil {
+			internalError(c, "decode error", err)
+			return
+		}
+		groups := make(map[primitive.ObjectID]string, len(ratees))
+		for _, u := range ratees {
+			groups[u.UserID] = u.Group
+		}
+
+		catalogues := map[string][]models.SkillDef{}
+		ratings := make([]models.PeerRating, 0, len(req.Ratings))
+		now := time.Now()
+		for _, r := range req.Ratings {
+			group, ok := groups[r.UserID]
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "player " + r.UserID.Hex() + " no longer exists"})
+				return
+			}
+			if _, ok := catalogues[group]; !ok {
+				defs, err := loadSkillCatalogue(ctx, db, group)
+				if err != nil {
+					internalError(c, "db error", err)
+					return
+				}
+				catalogues[group] = defs
+			}
+
+			skills, msg := validateSkills(catalogues[group], r.Skills)
+			if msg != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+				return
+			}
+			ratings = append(ratings, models.PeerRating{
+				PollID:    pollOID,
+				RaterID:   raterOID,
+				RateeID:   r.UserID,
+				Skills:    skills,
+				CreatedAt: now,
+			})
+		}
+
+		// 4️⃣ Store
+		for _, r := range ratings {
+			if _, err := db.Collection("peer_ratings").ReplaceOne(ctx,
+				bson.M{"pollId": r.PollID, "raterId": r.RaterID, "rateeId": r.RateeID},
+				r,
+				options.Replace().SetUpsert(true),
+			); err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"success": true, "rated": len(ratings)})
+	}
+}
+
+// calibratedProfiles works out calibrated skills for users, along with the
+// catalogue used for each group.
+func calibratedProfiles(ctx context.Context, db *mongo.Database, users []models.User) (map[primitive.ObjectID][]models.CalibratedSkill, map[string][]models.SkillDef, error) {
+	ids := make([]primitive.ObjectID, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.UserID)
+	}
+
+	// Newest first, so the first rating seen from a rater is the one kept:
+	// one enthusiastic teammate can't outvote the rest by rating every week.
+	cur, err := db.Collection("peer_ratings").Find(ctx,
+		bson.M{"rateeId": bson.M{"$in": ids}},
+		options.Find().SetSort(bson.M{"createdAt": -1}),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ratings []models.PeerRating
+	if err := cur.All(ctx, &ratings); err != nil {
+		return nil, nil, err
+	}
+
+	type pair struct{ rater, ratee primitive.ObjectID }
+	seen := map[pair]bool{}
+	peers := map[primitive.ObjectID]map[string][]int{}
+	for _, r := range ratings {
+		p := pair{r.RaterID, r.RateeID}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		if peers[r.RateeID] == nil {
+			peers[r.RateeID] = map[string][]int{}
+		}
+		for _, s := range r.Skills {
+			peers[r.RateeID][s.Name] = append(peers[r.RateeID][s.Name], s.Value)
+		}
+	}
+
+	catalogues := map[string][]models.SkillDef{}
+	profiles := make(map[primitive.ObjectID][]models.CalibratedSkill, len(users))
+	for _, u := range users {
+		if _, ok := catalogues[u.Group]; !ok {
+			defs, err := loadSkillCatalogue(ctx, db, u.Group)
+			if err != nil {
+				return nil, nil, err
+			}
+			catalogues[u.Group] = defs
+		}
+		profiles[u.UserID] = models.Calibrate(u.Skills, peers[u.UserID], catalogues[u.Group])
+	}
+	return profiles, catalogues, nil
+}
+
+// GetCalibratedSkills returns a user's skills blended from their own
+// assessment and teammates' ratings, as used for team generation.
+func GetCalibratedSkills(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return
+		}
+
+		user, ok := findUser(c, db, userOID)
+		if !ok {
+			return
+		}
+
+		profiles, catalogues, err := calibratedProfiles(context.Background(), db, []models.User{user})
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"userId":         user.UserID.Hex(),
+			"skills":         profiles[user.UserID],
+			"score":          models.CalibratedScore(profiles[user.UserID], catalogues[user.Group]),
+			"minPeerSamples": models.MinPeerSamples,
+		})
+	}
+}
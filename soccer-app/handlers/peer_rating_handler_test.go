@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSubmitPeerRatingsTeammatesOnly(t *testing.T) {
+	db := testDB(t)
+	alice := insertUser(t, db, models.User{Username: "alice"}, "x")
+	bob := insertUser(t, db, models.User{Username: "bob"}, "x")
+	carol := insertUser(t, db, models.User{Username: "carol"}, "x")
+
+	res, err := db.Collection("polls").InsertOne(context.Background(), models.Poll{
+		Status: "OPEN",
+		EndsAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pollID := res.InsertedID.(primitive.ObjectID)
+	if _, err := db.Collection("teams").InsertOne(context.Background(), models.PollTeams{
+		PollID: pollID,
+		TeamA:  []models.User{alice, bob},
+		TeamB:  []models.User{carol},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.POST("/polls/:id/ratings", AuthRequired(db), SubmitPeerRatings(db))
+	path := "/polls/" + pollID.Hex() + "/ratings"
+	token := insertSession(t, db, alice)
+	rate := func(u models.User) gin.H {
+		return gin.H{"ratings": []gin.H{{"userId": u.UserID, "skills": []models.Skill{{Name: "speed", Value: 7}}}}}
+	}
+
+	if w := doJSON(t, r, "POST", path, token, rate(bob)); w.Code != http.StatusConflict {
+		t.Fatalf("before the match: status = %d, want 409: %s", w.Code, w.Body)
+	}
+	if _, err := db.Collection("polls").UpdateOne(context.Background(),
+		bson.M{"_id": pollID}, bson.M{"$set": bson.M{"endsAt": time.Now().Add(-time.Hour)}},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := doJSON(t, r, "POST", path, token, rate(carol)); w.Code != http.StatusBadRequest {
+		t.Fatalf("opponent: status = %d, want 400", w.Code)
+	}
+	if w := doJSON(t, r, "POST", path, token, rate(bob)); w.Code != http.StatusOK {
+		t.Fatalf("teammate: status = %d, want 200: %s", w.Code, w.Body)
+	}
+}
@@ -2,27 +2,376 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"soccer-app/models"
 )
 
+// playerView is a user as shown in the players list.
+func playerView(u models.User) gin.H {
+	v := publicUser(u)
+	v["name"] = u.FullName()
+	v["avgRating"] = u.AvgRating
+	return v
+}
+
+const (
+	maxNameLength     = 50
+	maxPositionLength = 30
+	maxAvgRating      = 10
+)
+
+// playerSorts maps ?sort= to sort keys. Each ends with _id so cursors are
+// unambiguous.
+var playerSorts = map[string]bson.D{
+	"name":    {{Key: "firstName", Value: 1}, {Key: "lastName", Value: 1}, {Key: "_id", Value: 1}},
+	"rating":  {{Key: "avgRating", Value: -1}, {Key: "_id", Value: 1}},
+	"-rating": {{Key: "avgRating", Value: 1}, {Key: "_id", Value: 1}},
+}
+
+// validatePlayer checks fields shared by player create and update. A
+// non-empty message means the input was rejected.
+func validatePlayer(firstName, lastName, position string, avgRating float64) string {
+	switch {
+	case firstName == "":
+		return "name is required"
+	case len(firstName) > maxNameLength || len(lastName) > maxNameLength:
+		return "names can be at most " + strconv.Itoa(maxNameLength) + " characters"
+	case len(position) > maxPositionLength:
+		return "position can be at most " + strconv.Itoa(maxPositionLength) + " characters"
+	case avgRating < 0 || avgRating > maxAvgRating:
+		return "avgRating must be between 0 and " + strconv.Itoa(maxAvgRating)
+	}
+	return ""
+}
+
+// GetPlayers lists players, optionally filtered by ?position=, ?name=
+// (matches first, last or user name) and ?group=. ?sort= is "name"
+// (default), "rating" (highest first) or "-rating". Results are paged with
+// ?limit= and the nextCursor from the previous page in ?cursor=. Signed-in
+// users and players:read keys only, since it lists every username.
 func GetPlayers(db *mongo.Database) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var players []models.Player
-		cur, _ := db.Collection("players").Find(context.Background(), map[string]interface{}{})
-		cur.All(context.Background(), &players)
-		c.JSON(http.StatusOK, players)
+		ctx := context.Background()
+
+		// 1️⃣ Filters
+		filter := bson.M{}
+		if pos := strings.TrimSpace(c.Query("position")); pos != "" {
+			filter["position"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(pos) + "$", Options: "i"}
+		}
+		if name := strings.TrimSpace(c.Query("name")); name != "" {
+			re := primitive.Regex{Pattern: regexp.QuoteMeta(name), Options: "i"}
+			filter["$or"] = bson.A{
+				bson.M{"firstName": re},
+				bson.M{"lastName": re},
+				bson.M{"username": re},
+			}
+		}
+		if group := strings.TrimSpace(c.Query("group")); group != "" {
+			filter["group"] = strings.ToLower(group)
+		}
+
+		// 2️⃣ Sort and page
+		sortKey := c.DefaultQuery("sort", "name")
+		sort, ok := playerSorts[sortKey]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be name, rating or -rating"})
+			return
+		}
+		limit, err := pageSize(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if raw := c.Query("cursor"); raw != "" {
+			after, err := decodeCursor(raw, sort)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			filter = bson.M{"$and": bson.A{filter, afterCursor(sort, after)}}
+		}
+
+		cur, err := db.Collection("users").Find(ctx, filter,
+			options.Find().SetSort(sort).SetLimit(limit+1),
+		)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		defer cur.Close(ctx)
+
+		var users []models.User
+		if err := cur.All(ctx, &users); err != nil {
+			internalError(c, "decode error", err)
+			return
+		}
+
+		// 3️⃣ One extra row tells us whether there is another page
+		var next string
+		if int64(len(users)) > limit {
+			users = users[:limit]
+			last := users[len(users)-1]
+			values := map[string]interface{}{
+				"firstName": last.FirstName,
+				"lastName":  last.LastName,
+				"avgRating": last.AvgRating,
+				"_id":       last.UserID,
+			}
+			d := make(bson.D, 0, len(sort))
+			for _, s := range sort {
+				d = append(d, bson.E{Key: s.Key, Value: values[s.Key]})
+			}
+			next = encodeCursor(d)
+		}
+
+		players := make([]gin.H, 0, len(users))
+		for _, u := range users {
+			players = append(players, playerView(u))
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"players":    players,
+			"nextCursor": next,
+		})
 	}
 }
 
+// GetPlayer returns a single player.
+func GetPlayer(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player id"})
+			return
+		}
+
+		user, ok := findUser(c, db, userOID)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, playerView(user))
+	}
+}
+
+// CreatePlayer adds a player who has no login yet. Organiser only. They
+// get a username derived from their name and can sign in once issued a
+// reset code.
 func CreatePlayer(db *mongo.Database) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var p models.Player
-		c.ShouldBindJSON(&p)
-		db.Collection("players").InsertOne(context.Background(), p)
-		c.JSON(http.StatusCreated, p)
+		ctx := context.Background()
+
+		var req struct {
+			Name      string  `json:"name"`
+			FirstName string  `json:"firstName"`
+			LastName  string  `json:"lastName"`
+			Position  string  `json:"position"`
+			Group     string  `json:"group"`
+			AvgRating float64 `json:"avgRating"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if req.FirstName == "" && req.LastName == "" {
+			req.FirstName, req.LastName = models.SplitName(req.Name)
+		}
+		req.FirstName = strings.TrimSpace(req.FirstName)
+		req.LastName = strings.TrimSpace(req.LastName)
+		req.Position = strings.TrimSpace(req.Position)
+		if msg := validatePlayer(req.FirstName, req.LastName, req.Position, req.AvgRating); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		group := strings.ToLower(strings.TrimSpace(req.Group))
+		if group == "" {
+			group = models.DefaultGroup
+		}
+
+		user := models.User{
+			FirstName: req.FirstName,
+			LastName:  req.LastName,
+			Group:     group,
+			Position:  req.Position,
+			AvgRating: req.AvgRating,
+			Role:      models.RolePlayer,
+			CreatedAt: time.Now(),
+		}
+
+		// Try name-derived handles until one is free
+		base := models.HandleFromName(user.FirstName, user.LastName)
+		var res *mongo.InsertOneResult
+		var err error
+		for n := 1; n <= 100; n++ {
+			user.Username = base
+			if n > 1 {
+				user.Username = fmt.Sprintf("%s%d", base, n)
+			}
+			res, err = db.Collection("users").InsertOne(ctx, user)
+			if !mongo.IsDuplicateKeyError(err) {
+				break
+			}
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		user.UserID = res.InsertedID.(primitive.ObjectID)
+
+		actor, _ := currentUserID(c)
+		writeAudit(ctx, db, "player.created", actor, user.UserID, c.ClientIP(), "")
+
+		c.JSON(http.StatusCreated, playerView(user))
+	}
+}
+
+// UpdatePlayer edits a player's name, position, group or rating.
+// Organiser only, and admin only for players who have an account; skills
+// go through the skill review flow instead.
+func UpdatePlayer(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		userOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player id"})
+			return
+		}
+
+		var req struct {
+			FirstName *string  `json:"firstName"`
+			LastName  *string  `json:"lastName"`
+			Position  *string  `json:"position"`
+			Group     *string  `json:"group"`
+			AvgRating *float64 `json:"avgRating"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		user, ok := findUser(c, db, userOID)
+		if !ok {
+			return
+		}
+		// Moving an account holder to another group changes what they can
+		// see and who can manage them
+		if user.HasCredentials() && !models.HasRole(c.GetString("role"), models.RoleAdmin) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "player has an account; only an admin can edit it"})
+			return
+		}
+
+		set := bson.M{}
+		if req.FirstName != nil {
+			user.FirstName = strings.TrimSpace(*req.FirstName)
+			set["firstName"] = user.FirstName
+		}
+		if req.LastName != nil {
+			user.LastName = strings.TrimSpace(*req.LastName)
+			set["lastName"] = user.LastName
+		}
+		if req.Position != nil {
+			user.Position = strings.TrimSpace(*req.Position)
+			set["position"] = user.Position
+		}
+		if req.Group != nil {
+			user.Group = strings.ToLower(strings.TrimSpace(*req.Group))
+			if user.Group == "" {
+				user.Group = models.DefaultGroup
+			}
+			set["group"] = user.Group
+		}
+		if req.AvgRating != nil {
+			user.AvgRating = *req.AvgRating
+			set["avgRating"] = user.AvgRating
+		}
+		if len(set) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
+			return
+		}
+		if msg := validatePlayer(user.FirstName, user.LastName, user.Position, user.AvgRating); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		res, err := db.Collection("users").UpdateOne(ctx, bson.M{"_id": userOID}, bson.M{"$set": set})
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if res.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "player not found"})
+			return
+		}
+
+		actor, _ := currentUserID(c)
+		writeAudit(ctx, db, "player.updated", actor, userOID, c.ClientIP(), "")
+
+		c.JSON(http.StatusOK, playerView(user))
 	}
-}
\ No newline at end of file
+}
+
+// DeletePlayer removes a player who never set up a login. Organiser
+// only; accounts with credentials are deleted through DELETE /users/:id.
+func DeletePlayer(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player id"})
+			return
+		}
+
+		user, ok := findUser(c, db, userOID)
+		if !ok {
+			return
+		}
+		if user.HasCredentials() {
+			c.JSON(http.StatusConflict, gin.H{"error": "player has an account; an admin must delete it via /users/:id"})
+			return
+		}
+
+		if err := deleteUser(context.Background(), db, user); err != nil {
+			internalError(c, "failed to delete player", err)
+			return
+		}
+
+		actor, _ := currentUserID(c)
+		writeAudit(context.Background(), db, "player.deleted", actor, userOID, c.ClientIP(), user.FullName())
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// ListPlayerMergeConflicts returns old player records that matched more
+// than one user during migration. Admin only.
+func ListPlayerMergeConflicts(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		cur, err := db.Collection("player_merge_conflicts").Find(ctx, bson.M{})
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		defer cur.Close(ctx)
+
+		conflicts := []models.PlayerMergeConflict{}
+		if err := cur.All(ctx, &conflicts); err != nil {
+			internalError(c, "decode error", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, conflicts)
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUpdatePlayerWithAccountNeedsAdmin(t *testing.T) {
+	db := testDB(t)
+	org := insertUser(t, db, models.User{Username: "olive", Group: "tuesday", Role: models.RoleOrganiser}, "olive-secret")
+	admin := insertUser(t, db, models.User{Username: "ada", Role: models.RoleAdmin}, "ada-secret")
+	member := insertUser(t, db, models.User{Username: "alice", FirstName: "Alice", Group: "tuesday"}, "alice-secret")
+
+	for _, tc := range []struct {
+		name  string
+		actor models.User
+		want  int
+	}{
+		{"organiser", org, http.StatusForbidden},
+		{"admin", admin, http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.PUT("/players/:id", func(c *gin.Context) {
+				setIdentity(c, tc.actor)
+			}, UpdatePlayer(db))
+
+			w := doJSON(t, r, http.MethodPut, "/players/"+member.UserID.Hex(), "", gin.H{"group": "sunday"})
+			if w.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tc.want, w.Body)
+			}
+		})
+	}
+}
@@ -15,7 +15,7 @@ import (
 type createPollReq struct {
 	// Optional. If empty, defaults to next Saturday.
 	PollDate string `json:"pollDate"`
-	// Optional. If empty, defaults to Saturday 10:00 AM America/Chicago.
+	// Optional. If empty, defaults to Saturday 10:00 AM in the configured time zone.
 	EndsAt string `json:"endsAt"` // RFC3339 recommended
 }
 
@@ -30,9 +30,8 @@ func nextSaturdayDate(loc *time.Location) time.Time {
 	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc)
 }
 
-func CreatePoll(db *mongo.Database) gin.HandlerFunc {
+func CreatePoll(db *mongo.Database, loc *time.Location) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		loc, _ := time.LoadLocation("America/Chicago")
 
 		var req createPollReq
 		_ = c.ShouldBindJSON(&req)
@@ -74,7 +73,7 @@ func CreatePoll(db *mongo.Database) gin.HandlerFunc {
 
 		_, err := db.Collection("polls").InsertOne(context.Background(), poll)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db insert failed"})
+			internalError(c, "db insert failed", err)
 			return
 		}
 
@@ -82,9 +81,8 @@ func CreatePoll(db *mongo.Database) gin.HandlerFunc {
 	}
 }
 
-func GetCurrentPoll(db *mongo.Database) gin.HandlerFunc {
+func GetCurrentPoll(db *mongo.Database, loc *time.Location) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		loc, _ := time.LoadLocation("America/Chicago")
 		now := time.Now().In(loc)
 
 		var poll models.Poll
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// pollMetricsTimeout bounds the queries made for one scrape.
+const pollMetricsTimeout = 3 * time.Second
+
+var (
+	openPollsDesc    = prometheus.NewDesc("soccer_open_polls", "Polls open for votes.", nil, nil)
+	currentVotesDesc = prometheus.NewDesc("soccer_current_poll_votes",
+		"Votes on the current poll, by whether the player is attending.", []string{"attending"}, nil)
+)
+
+// PollCollector reports poll gauges, read from Mongo on each scrape so
+// they are never stale and cost nothing between scrapes.
+type PollCollector struct {
+	db  *mongo.Database
+	loc *time.Location
+}
+
+func NewPollCollector(db *mongo.Database, loc *time.Location) *PollCollector {
+	return &PollCollector{db: db, loc: loc}
+}
+
+func (p *PollCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- openPollsDesc
+	ch <- currentVotesDesc
+}
+
+// Collect skips whatever it can't read rather than failing the scrape.
+func (p *PollCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), pollMetricsTimeout)
+	defer cancel()
+
+	// Same filter as GetCurrentPoll
+	open := bson.M{"status": "OPEN", "endsAt": bson.M{"$gt": time.Now().In(p.loc)}}
+
+	n, err := p.db.Collection("polls").CountDocuments(ctx, open)
+	if err != nil {
+		slog.Warn("poll metrics: count failed", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(openPollsDesc, prometheus.GaugeValue, float64(n))
+
+	var poll models.Poll
+	if err := p.db.Collection("polls").FindOne(ctx, open).Decode(&poll); err != nil {
+		if err != mongo.ErrNoDocuments {
+			slog.Warn("poll metrics: current poll lookup failed", "error", err)
+		}
+		return
+	}
+
+	cur, err := p.db.Collection("votes").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"pollId": poll.ID}}},
+		{{Key: "$group", Value: bson.M{"_id": "$attending", "n": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		slog.Warn("poll metrics: vote count failed", "error", err)
+		return
+	}
+	var groups []struct {
+		Attending bool `bson:"_id"`
+		N         int  `bson:"n"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		slog.Warn("poll metrics: vote count failed", "error", err)
+		return
+	}
+
+	counts := map[bool]int{}
+	for _, g := range groups {
+		counts[g.Attending] += g.N
+	}
+	ch <- prometheus.MustNewConstMetric(currentVotesDesc, prometheus.GaugeValue, float64(counts[true]), "true")
+	ch <- prometheus.MustNewConstMetric(currentVotesDesc, prometheus.GaugeValue, float64(counts[false]), "false")
+}
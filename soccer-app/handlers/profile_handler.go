@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UpdateMyProfile lets the authenticated user change their own profile.
+// Names, email and position are applied immediately; skill changes are
+// queued for an organiser to approve.
+func UpdateMyProfile(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		userOID, ok := currentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		var req struct {
+			FirstName *string        `json:"firstName"`
+			LastName  *string        `json:"lastName"`
+			Email     *string        `json:"email"`
+			Position  *string        `json:"position"`
+			Skills    []models.Skill `json:"skills"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		var user models.User
+		err := db.Collection("users").FindOne(ctx, bson.M{"_id": userOID}).Decode(&user)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		// 1️⃣ Validate everything before changing anything
+		set, unset, msg := profileChanges(req.FirstName, req.LastName, req.Email)
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		if req.Position != nil {
+			set["position"] = *req.Position
+		}
+
+		var skills []models.Skill
+		if req.Skills != nil {
+			catalogue, err := loadSkillCatalogue(ctx, db, user.Group)
+			if err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+			if skills, msg = validateSkills(catalogue, req.Skills); msg != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+				return
+			}
+		}
+
+		// 2️⃣ Names, email and position: no review needed
+		update := bson.M{}
+		if len(set) > 0 {
+			update["$set"] = set
+		}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+		if len(update) > 0 {
+			_, err := db.Collection("users").UpdateOne(ctx, bson.M{"_id": userOID}, update)
+			if mongo.IsDuplicateKeyError(err) {
+				c.JSON(http.StatusConflict, gin.H{"error": "email already taken"})
+				return
+			}
+			if err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+		}
+
+		// 3️⃣ Skills: replace any pending request with the new one
+		pending := false
+		if skills != nil {
+			_, err = db.Collection("skill_changes").UpdateOne(
+				ctx,
+				bson.M{"userId": userOID, "status": "PENDING"},
+				bson.M{
+					"$set": bson.M{
+						"username":    user.Username,
+						"previous":    user.Skills,
+						"skills":      skills,
+						"requestedAt": time.Now(),
+					},
+					"$setOnInsert": bson.M{
+						"userId": userOID,
+						"status": "PENDING",
+					},
+				},
+				options.Update().SetUpsert(true),
+			)
+			if mongo.IsDuplicateKeyError(err) {
+				c.JSON(http.StatusConflict, gin.H{"error": "another skill change was just submitted, try again"})
+				return
+			}
+			if err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+			pending = true
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":       true,
+			"skillsPending": pending,
+		})
+	}
+}
+
+// ListSkillChanges returns skill change requests, pending ones by default.
+// Organiser only.
+func ListSkillChanges(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		status := c.DefaultQuery("status", "PENDING")
+		switch status {
+		case "PENDING", "APPROVED", "REJECTED":
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be PENDING, APPROVED or REJECTED"})
+			return
+		}
+
+		cur, err := db.Collection("skill_changes").Find(
+			ctx,
+			bson.M{"status": status},
+			options.Find().SetSort(bson.M{"requestedAt": 1}),
+		)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		defer cur.Close(ctx)
+
+		changes := []models.SkillChange{}
+		if err := cur.All(ctx, &changes); err != nil {
+			internalError(c, "decode error", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, changes)
+	}
+}
+
+// ApproveSkillChange applies a pending skill change to the user. Organiser
+// only, and never for the organiser's own change.
+func ApproveSkillChange(db *mongo.Database) gin.HandlerFunc {
+	return reviewSkillChange(db, "APPROVED")
+}
+
+// RejectSkillChange discards a pending skill change. Organiser only, and
+// never for the organiser's own change.
+func RejectSkillChange(db *mongo.Database) gin.HandlerFunc {
+	return reviewSkillChange(db, "REJECTED")
+}
+
+func reviewSkillChange(db *mongo.Database, status string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		changeOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid skill change id"})
+			return
+		}
+
+		reviewer, _ := currentUserID(c)
+		now := time.Now()
+		filter := bson.M{"_id": changeOID, "status": "PENDING", "userId": bson.M{"$ne": reviewer}}
+
+		// 1️⃣ Skills retired or re-ranged since the request can't be approved
+		if status == "APPROVED" {
+			var pending models.SkillChange
+			err := db.Collection("skill_changes").FindOne(ctx, filter).Decode(&pending)
+			if err != nil && err != mongo.ErrNoDocuments {
+				internalError(c, "db error", err)
+				return
+			}
+			if err == nil {
+				user, ok := findUser(c, db, pending.UserID)
+				if !ok {
+					return
+				}
+				catalogue, err := loadSkillCatalogue(ctx, db, user.Group)
+				if err != nil {
+					internalError(c, "db error", err)
+					return
+				}
+				if _, msg := validateSkills(catalogue, pending.Skills); msg != "" {
+					c.JSON(http.StatusConflict, gin.H{"error": "skill change no longer fits the catalogue (" + msg + "), reject it instead"})
+					return
+				}
+				// Approve exactly what was checked, not a newer request
+				filter["requestedAt"] = pending.RequestedAt
+			}
+		}
+
+		// 2️⃣ Close the request (only if still pending and not the reviewer's own)
+		var change models.SkillChange
+		err = db.Collection("skill_changes").FindOneAndUpdate(
+			ctx,
+			filter,
+			bson.M{"$set": bson.M{
+				"status":     status,
+				"reviewedBy": reviewer,
+				"reviewedAt": now,
+			}},
+		).Decode(&change)
+		if err == mongo.ErrNoDocuments {
+			own, err := db.Collection("skill_changes").CountDocuments(ctx, bson.M{"_id": changeOID, "status": "PENDING", "userId": reviewer})
+			if err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+			if own > 0 {
+				c.JSON(http.StatusForbidden, gin.H{"error": "cannot review your own skill change"})
+				return
+			}
+			c.JSON(http.StatusNotFound, gin.H{"error": "pending skill change not found"})
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		// 3️⃣ Apply approved skills
+		if status == "APPROVED" {
+			if _, err := db.Collection("users").UpdateOne(
+				ctx,
+				bson.M{"_id": change.UserID},
+				bson.M{"$set": bson.M{"skills": change.Skills}},
+			); err != nil {
+				internalError(c, "failed to apply skills", err)
+				return
+			}
+		}
+
+		writeAudit(ctx, db, "skill_change."+strings.ToLower(status), reviewer, change.UserID, c.ClientIP(), change.ID.Hex())
+
+		c.JSON(http.StatusOK, gin.H{"id": change.ID.Hex(), "status": status})
+	}
+}
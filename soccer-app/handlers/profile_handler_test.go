@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReviewSkillChange(t *testing.T) {
+	db := testDB(t)
+	olive := insertUser(t, db, models.User{Username: "olive", Role: models.RoleOrganiser}, "x")
+	oscar := insertUser(t, db, models.User{Username: "oscar", Role: models.RoleOrganiser}, "y")
+
+	res, err := db.Collection("skill_changes").InsertOne(context.Background(), models.SkillChange{
+		UserID:      olive.UserID,
+		Username:    olive.Username,
+		Skills:      []models.Skill{{Name: "speed", Value: 10}},
+		Status:      "PENDING",
+		RequestedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/skill-changes/" + res.InsertedID.(primitive.ObjectID).Hex() + "/approve"
+
+	r := gin.New()
+	r.GET("/skill-changes", AuthRequired(db), ListSkillChanges(db))
+	r.POST("/skill-changes/:id/approve", AuthRequired(db), ApproveSkillChange(db))
+
+	if w := doJSON(t, r, "POST", path, insertSession(t, db, olive), nil); w.Code != http.StatusForbidden {
+		t.Fatalf("own change: status = %d, want 403", w.Code)
+	}
+	if w := doJSON(t, r, "POST", path, insertSession(t, db, oscar), nil); w.Code != http.StatusOK {
+		t.Fatalf("someone else's change: status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	token := insertSession(t, db, oscar)
+	if w := doJSON(t, r, "GET", "/skill-changes?status=APPROVED", token, nil); w.Code != http.StatusOK {
+		t.Fatalf("status=APPROVED: status = %d, want 200", w.Code)
+	}
+	if w := doJSON(t, r, "GET", "/skill-changes?status=anything", token, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("status=anything: status = %d, want 400", w.Code)
+	}
+}
+
+func TestUpdateMyProfileValidatesBeforeSaving(t *testing.T) {
+	db := testDB(t)
+	alice := insertUser(t, db, models.User{Username: "alice", FirstName: "Alice"}, "x")
+
+	r := gin.New()
+	r.PUT("/users/me", AuthRequired(db), UpdateMyProfile(db))
+
+	w := doJSON(t, r, "PUT", "/users/me", insertSession(t, db, alice), gin.H{
+		"firstName": "Alicia",
+		"skills":    []models.Skill{{Name: "juggling", Value: 5}},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", w.Code, w.Body)
+	}
+
+	var got models.User
+	if err := db.Collection("users").FindOne(context.Background(), bson.M{"_id": alice.UserID}).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.FirstName != "Alice" {
+		t.Fatalf("firstName = %q after a rejected request, want unchanged", got.FirstName)
+	}
+}
+
+func TestApproveStaleSkillChange(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	alice := insertUser(t, db, models.User{Username: "alice", Group: "tuesday"}, "x")
+	olive := insertUser(t, db, models.User{Username: "olive", Group: "tuesday", Role: models.RoleOrganiser}, "y")
+
+	// The group's catalogue no longer has the requested skill
+	if _, err := db.Collection("skills").InsertOne(ctx, models.SkillDef{Group: "tuesday", Name: "passing", Min: 1, Max: 10, CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := db.Collection("skill_changes").InsertOne(ctx, models.SkillChange{
+		UserID:      alice.UserID,
+		Username:    alice.Username,
+		Skills:      []models.Skill{{Name: "speed", Value: 5}},
+		Status:      "PENDING",
+		RequestedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.POST("/skill-changes/:id/approve", AuthRequired(db), ApproveSkillChange(db))
+	path := "/skill-changes/" + res.InsertedID.(primitive.ObjectID).Hex() + "/approve"
+	if w := doJSON(t, r, "POST", path, insertSession(t, db, olive), nil); w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409: %s", w.Code, w.Body)
+	}
+
+	var got models.User
+	if err := db.Collection("users").FindOne(ctx, bson.M{"_id": alice.UserID}).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Skills) != 0 {
+		t.Fatalf("skills = %v, want none applied", got.Skills)
+	}
+}
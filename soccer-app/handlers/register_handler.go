@@ -11,20 +11,10 @@ import (
 	"soccer-app/models"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var allowedSkills = map[string]bool{
-	"speed":     true,
-	"dribbling": true,
-	"shooting":  true,
-	"defending": true,
-	"passing":   true,
-	"stamina":   true,
-}
-
 func HashSecret(secret string) string {
 	sum := sha256.Sum256([]byte(secret))
 	return hex.EncodeToString(sum[:])
@@ -34,11 +24,15 @@ func RegisterUser(db *mongo.Database) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var req struct {
-			FirstName string         `json:"firstName"`
-			LastName  string         `json:"lastName"`
-			Position  string         `json:"position"`
-			Skills    []models.Skill `json:"skills"`
-			Secret    string         `json:"secret"`
+			Username   string         `json:"username"`
+			Email      string         `json:"email"`
+			FirstName  string         `json:"firstName"`
+			LastName   string         `json:"lastName"`
+			Position   string         `json:"position"`
+			Skills     []models.Skill `json:"skills"`
+			Secret     string         `json:"secret"`
+			Group      string         `json:"group"`
+			InviteCode string         `json:"inviteCode"`
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -51,64 +45,118 @@ func RegisterUser(db *mongo.Database) gin.HandlerFunc {
 			return
 		}
 
-		if len(req.Skills) == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "at least one skill is required"})
+		req.Username = models.NormalizeHandle(req.Username)
+		if !models.ValidHandle(req.Username) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "username must be 3-30 characters of a-z, 0-9, '.', '_' or '-'"})
 			return
 		}
 
-		// ✅ Validate skills
-		for _, s := range req.Skills {
-			name := strings.ToLower(s.Name)
+		req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+		if req.Email != "" && !strings.Contains(req.Email, "@") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email"})
+			return
+		}
 
-			if !allowedSkills[name] {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "invalid skill: " + s.Name,
-				})
+		ctx := context.Background()
+		group := strings.ToLower(strings.TrimSpace(req.Group))
+		role := models.RolePlayer
+
+		// 🎟️ Invite code: decides group/role and is required for
+		// invite-only groups
+		var invite *models.Invite
+		registered := false
+		if req.InviteCode != "" {
+			inv, err := redeemInvite(ctx, db, req.InviteCode)
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusForbidden, gin.H{"error": "invalid or expired invite code"})
 				return
 			}
-
-			if s.Value < 1 || s.Value > 10 {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "skill value must be between 1 and 10",
-				})
+			if err != nil {
+				internalError(c, "db error", err)
 				return
 			}
-
-			s.Name = name
+			// Give the use back unless the account gets created
+			defer func() {
+				if !registered {
+					releaseInvite(ctx, db, inv.ID)
+				}
+			}()
+			if inv.Group != "" && group != "" && inv.Group != group {
+				c.JSON(http.StatusForbidden, gin.H{"error": "invite code is for a different group"})
+				return
+			}
+			if inv.Group != "" {
+				group = inv.Group
+			}
+			if inv.Role != "" {
+				role = inv.Role
+			}
+			invite = &inv
 		}
 
-		filter := bson.M{
-			"firstName": req.FirstName,
-			"lastName":  req.LastName,
+		if group == "" {
+			group = models.DefaultGroup
 		}
 
-		update := bson.M{
-			"$setOnInsert": bson.M{
-				"firstName":  req.FirstName,
-				"lastName":   req.LastName,
-				"secretHash": HashSecret(req.Secret),
-				"createdAt":  time.Now(),
-			},
-			"$set": bson.M{
-				"position": req.Position,
-				"skills":   req.Skills,
-			},
+		// Invite-only groups need an invite issued for that very group;
+		// older invites without a group don't count
+		inviteOnly, err := groupInviteOnly(ctx, db, group)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if inviteOnly && invite == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "an invite code is required to join this group"})
+			return
+		}
+		if inviteOnly && invite.Group != group {
+			c.JSON(http.StatusForbidden, gin.H{"error": "invite code is for a different group"})
+			return
 		}
 
-		opts := options.Update().SetUpsert(true)
+		catalogue, err := loadSkillCatalogue(ctx, db, group)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		skills, msg := validateSkills(catalogue, req.Skills)
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
 
-		_, err := db.Collection("users").UpdateOne(
-			context.Background(),
-			filter,
-			update,
-			opts,
-		)
+		user := models.User{
+			Username:   req.Username,
+			Email:      req.Email,
+			FirstName:  req.FirstName,
+			LastName:   req.LastName,
+			Group:      group,
+			Position:   req.Position,
+			Skills:     skills,
+			Role:       role,
+			SecretHash: HashSecret(req.Secret),
+			CreatedAt:  time.Now(),
+		}
 
+		// Insert only: an existing username or email is never overwritten.
+		// Profile changes go through UpdateMyProfile.
+		res, err := db.Collection("users").InsertOne(ctx, user)
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "username or email already taken"})
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			internalError(c, "db error", err)
 			return
 		}
 
+		registered = true
+		markIdentified(c, res.InsertedID.(primitive.ObjectID))
+
+		if invite != nil {
+			writeAudit(ctx, db, "invite.redeemed", res.InsertedID.(primitive.ObjectID), primitive.NilObjectID, c.ClientIP(), invite.ID.Hex())
+		}
+
 		c.JSON(http.StatusCreated, gin.H{"success": true})
 	}
 }
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const resetCodeTTL = 24 * time.Hour
+
+// IssueResetCode creates a one-time secret reset code for a user.
+// Organiser only, and only for users of a lower role: resetting an
+// organiser takes an admin, and no one can reset an admin. Any earlier
+// unused codes for the user are invalidated.
+func IssueResetCode(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		userOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return
+		}
+
+		target, ok := findUser(c, db, userOID)
+		if !ok {
+			return
+		}
+		// A reset code hands over the account, so it must not let anyone
+		// take over a peer or a superior
+		if !models.Outranks(c.GetString("role"), target.Role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "cannot issue a reset code for a user of equal or higher role"})
+			return
+		}
+
+		issuer, _ := currentUserID(c)
+		now := time.Now()
+
+		// 1️⃣ Invalidate older codes
+		if _, err := db.Collection("reset_codes").UpdateMany(
+			ctx,
+			bson.M{"userId": userOID, "usedAt": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"usedAt": now}},
+		); err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		// 2️⃣ Store the new code hashed
+		code, err := newOneTimeCode()
+		if err != nil {
+			internalError(c, "failed to generate code", err)
+			return
+		}
+
+		rc := models.ResetCode{
+			UserID:    userOID,
+			CodeHash:  HashSecret(code),
+			IssuedBy:  issuer,
+			CreatedAt: now,
+			ExpiresAt: now.Add(resetCodeTTL),
+		}
+		if _, err := db.Collection("reset_codes").InsertOne(ctx, rc); err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		writeAudit(ctx, db, "reset_code.issued", issuer, userOID, c.ClientIP(), "")
+
+		// 3️⃣ The raw code is only ever shown here
+		c.JSON(http.StatusCreated, gin.H{
+			"code":      code,
+			"expiresAt": rc.ExpiresAt,
+		})
+	}
+}
+
+// ResetSecret redeems a reset code and sets a new secret.
+func ResetSecret(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		var req struct {
+			Username  string `json:"username"`
+			Code      string `json:"code"`
+			NewSecret string `json:"newSecret"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+		req.Username = models.NormalizeHandle(req.Username)
+		if req.Username == "" || req.Code == "" || req.NewSecret == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "username, code and newSecret are required"})
+			return
+		}
+
+		acctKey := accountKey(req.Username)
+		clientKey := ipKey(c.ClientIP())
+
+		// ⏳ Codes are short, so guessing is throttled like logins
+		wait, err := lockedFor(ctx, db, acctKey, clientKey)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if wait > 0 {
+			respondLocked(c, wait)
+			return
+		}
+
+		fail := func() {
+			if err := recordFailures(ctx, db, acctKey, clientKey); err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired code"})
+		}
+
+		var user models.User
+		err = db.Collection("users").FindOne(ctx, bson.M{"username": req.Username}).Decode(&user)
+		if err == mongo.ErrNoDocuments {
+			fail()
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		// 1️⃣ Consume the code atomically so it can't be used twice
+		now := time.Now()
+		var rc models.ResetCode
+		err = db.Collection("reset_codes").FindOneAndUpdate(
+			ctx,
+			bson.M{
+				"userId":    user.UserID,
+				"codeHash":  HashSecret(normalizeOneTimeCode(req.Code)),
+				"usedAt":    bson.M{"$exists": false},
+				"expiresAt": bson.M{"$gt": now},
+			},
+			bson.M{"$set": bson.M{"usedAt": now}},
+		).Decode(&rc)
+		if err == mongo.ErrNoDocuments {
+			writeAudit(ctx, db, "reset_code.rejected", primitive.NilObjectID, user.UserID, c.ClientIP(), "")
+			fail()
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		// 2️⃣ Set the new secret
+		if _, err := db.Collection("users").UpdateOne(
+			ctx,
+			bson.M{"_id": user.UserID},
+			bson.M{
+				"$set":   bson.M{"secretHash": HashSecret(req.NewSecret), "loginHistoryFrom": time.Now()},
+				"$unset": bson.M{"reverifyRequired": ""},
+			},
+		); err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		// 3️⃣ Old sessions were opened with the old secret
+		if _, err := db.Collection("sessions").DeleteMany(ctx, bson.M{"userId": user.UserID}); err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if err := clearFailures(ctx, db, acctKey); err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		markIdentified(c, user.UserID)
+		writeAudit(ctx, db, "reset_code.redeemed", user.UserID, user.UserID, c.ClientIP(), rc.ID.Hex())
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
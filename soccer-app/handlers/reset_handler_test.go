@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIssueResetCodeRoleHierarchy(t *testing.T) {
+	db := testDB(t)
+	player := insertUser(t, db, models.User{Username: "pat"}, "x")
+	org := insertUser(t, db, models.User{Username: "olive", Role: models.RoleOrganiser}, "x")
+	org2 := insertUser(t, db, models.User{Username: "oscar", Role: models.RoleOrganiser}, "x")
+	admin := insertUser(t, db, models.User{Username: "ada", Role: models.RoleAdmin}, "x")
+	admin2 := insertUser(t, db, models.User{Username: "alan", Role: models.RoleAdmin}, "x")
+
+	cases := []struct {
+		name   string
+		caller models.User
+		target models.User
+		want   int
+	}{
+		{"organiser resets player", org, player, http.StatusCreated},
+		{"organiser resets organiser", org, org2, http.StatusForbidden},
+		{"organiser resets admin", org, admin, http.StatusForbidden},
+		{"admin resets organiser", admin, org, http.StatusCreated},
+		{"admin resets admin", admin, admin2, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.POST("/users/:id/reset-codes", func(c *gin.Context) {
+				setIdentity(c, tc.caller)
+			}, IssueResetCode(db))
+
+			w := doJSON(t, r, http.MethodPost, "/users/"+tc.target.UserID.Hex()+"/reset-codes", "", nil)
+			if w.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tc.want, w.Body)
+			}
+		})
+	}
+}
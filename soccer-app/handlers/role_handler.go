@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GrantRole sets the role of a user. Admin only.
+func GrantRole(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return
+		}
+
+		var req struct {
+			Role string `json:"role"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if !models.ValidRole(req.Role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+			return
+		}
+
+		if !canDemote(c, db, userOID, req.Role) {
+			return
+		}
+
+		setUserRole(c, db, userOID, req.Role)
+	}
+}
+
+// RevokeRole resets a user back to the player role. Admin only.
+func RevokeRole(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return
+		}
+
+		if !canDemote(c, db, userOID, models.RolePlayer) {
+			return
+		}
+
+		setUserRole(c, db, userOID, models.RolePlayer)
+	}
+}
+
+// canDemote refuses role changes that would lock admins out: the caller
+// dropping their own admin role, or the last admin losing theirs. It
+// writes the response when it returns false.
+func canDemote(c *gin.Context, db *mongo.Database, userOID primitive.ObjectID, role string) bool {
+	if role == models.RoleAdmin {
+		return true
+	}
+
+	if me, ok := currentUserID(c); ok && me == userOID {
+		c.JSON(http.StatusConflict, gin.H{"error": "cannot change your own role"})
+		return false
+	}
+
+	return notLastAdmin(c, db, userOID)
+}
+
+// notLastAdmin refuses to let userOID stop being an admin when no other
+// admin is left. It writes the response when it returns false.
+func notLastAdmin(c *gin.Context, db *mongo.Database, userOID primitive.ObjectID) bool {
+	others, err := db.Collection("users").CountDocuments(context.Background(), bson.M{
+		"_id":  bson.M{"$ne": userOID},
+		"role": models.RoleAdmin,
+	})
+	if err != nil {
+		internalError(c, "db error", err)
+		return false
+	}
+	if others == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "cannot remove the last admin"})
+		return false
+	}
+	return true
+}
+
+func setUserRole(c *gin.Context, db *mongo.Database, userOID primitive.ObjectID, role string) {
+	res, err := db.Collection("users").UpdateOne(
+		context.Background(),
+		bson.M{"_id": userOID},
+		bson.M{"$set": bson.M{"role": role}},
+	)
+	if err != nil {
+		internalError(c, "db error", err)
+		return
+	}
+	if res.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"userId": userOID.Hex(), "role": role})
+}
+
+// EnsureAdmin promotes the given user to admin so a fresh deployment has
+// someone who can grant roles. It is a no-op if the user does not exist.
+func EnsureAdmin(db *mongo.Database, username string) error {
+	username = models.NormalizeHandle(username)
+	if username == "" {
+		return nil
+	}
+	_, err := db.Collection("users").UpdateOne(
+		context.Background(),
+		bson.M{"username": username},
+		bson.M{"$set": bson.M{"role": models.RoleAdmin}},
+	)
+	return err
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGrantRoleSelfDemotion(t *testing.T) {
+	db := testDB(t)
+	ada := insertUser(t, db, models.User{Username: "ada", Role: models.RoleAdmin}, "x")
+	bea := insertUser(t, db, models.User{Username: "bea", Role: models.RoleAdmin}, "x")
+
+	r := gin.New()
+	r.PUT("/admin/users/:id/role", AuthRequired(db), GrantRole(db))
+	token := insertSession(t, db, ada)
+	grant := func(u models.User, role string) int {
+		return doJSON(t, r, "PUT", "/admin/users/"+u.UserID.Hex()+"/role", token, gin.H{"role": role}).Code
+	}
+
+	if got := grant(ada, models.RolePlayer); got != http.StatusConflict {
+		t.Fatalf("own demotion: status = %d, want 409", got)
+	}
+	if got := grant(ada, models.RoleAdmin); got != http.StatusOK {
+		t.Fatalf("own role unchanged: status = %d, want 200", got)
+	}
+	if got := grant(bea, models.RoleOrganiser); got != http.StatusOK {
+		t.Fatalf("other admin: status = %d, want 200", got)
+	}
+}
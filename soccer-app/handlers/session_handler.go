@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// sessionView is what a user sees about one of their sessions.
+type sessionView struct {
+	ID         string        `json:"id"`
+	Device     models.Device `json:"device"`
+	IP         string        `json:"ip,omitempty"`
+	Country    string        `json:"country,omitempty"`
+	City       string        `json:"city,omitempty"`
+	MFA        bool          `json:"mfa"`
+	Current    bool          `json:"current"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	LastSeenAt time.Time     `json:"lastSeenAt"`
+	ExpiresAt  time.Time     `json:"expiresAt"`
+}
+
+// ListMySessions returns the authenticated user's active sessions, most
+// recently used first, with the device and rough location of each. The
+// location comes from the login event that opened the session.
+func ListMySessions(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		userOID, ok := currentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		cur, err := db.Collection("sessions").Find(ctx,
+			bson.M{"userId": userOID, "expiresAt": bson.M{"$gt": time.Now()}},
+			options.Find().SetSort(bson.D{{Key: "lastSeenAt", Value: -1}, {Key: "createdAt", Value: -1}}),
+		)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		defer cur.Close(ctx)
+
+		var sessions []models.Session
+		if err := cur.All(ctx, &sessions); err != nil {
+			internalError(c, "decode error", err)
+			return
+		}
+
+		// Location is best effort: the event is written in the background
+		// and expires with the access log
+		requestIDs := make([]string, 0, len(sessions))
+		for _, s := range sessions {
+			if s.RequestID != "" {
+				requestIDs = append(requestIDs, s.RequestID)
+			}
+		}
+		places := map[string]models.LoginEvent{}
+		if len(requestIDs) > 0 {
+			cur, err := db.Collection("login_events").Find(ctx,
+				bson.M{"userId": userOID, "signIn": true, "requestId": bson.M{"$in": requestIDs}},
+				options.Find().SetProjection(bson.M{"requestId": 1, "country": 1, "city": 1}),
+			)
+			if err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+			var events []models.LoginEvent
+			if err := cur.All(ctx, &events); err != nil {
+				internalError(c, "decode error", err)
+				return
+			}
+			for _, e := range events {
+				places[e.RequestID] = e
+			}
+		}
+
+		current := c.GetString("sessionId")
+		views := make([]sessionView, 0, len(sessions))
+		for _, s := range sessions {
+			v := sessionView{
+				ID:         s.ID.Hex(),
+				Device:     models.ParseUserAgent(s.UserAgent),
+				IP:         s.IP,
+				MFA:        s.MFA,
+				Current:    s.ID.Hex() == current,
+				CreatedAt:  s.CreatedAt,
+				LastSeenAt: s.LastSeenAt,
+				ExpiresAt:  s.ExpiresAt,
+			}
+			if e, ok := places[s.RequestID]; ok {
+				v.Country = e.Country
+				v.City = e.City
+			}
+			views = append(views, v)
+		}
+
+		c.JSON(http.StatusOK, views)
+	}
+}
+
+// RevokeMySession signs out one of the authenticated user's sessions.
+func RevokeMySession(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		userOID, ok := currentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+		sessionOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+			return
+		}
+
+		res, err := db.Collection("sessions").DeleteOne(ctx, bson.M{"_id": sessionOID, "userId": userOID})
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if res.DeletedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return
+		}
+
+		writeAudit(ctx, db, "session.revoked", userOID, userOID, c.ClientIP(), sessionOID.Hex())
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// RevokeMySessions signs out all of the authenticated user's other
+// sessions, or every session including this one with ?includeCurrent=true.
+func RevokeMySessions(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		userOID, ok := currentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		filter := bson.M{"userId": userOID}
+		if c.Query("includeCurrent") != "true" {
+			if current, err := primitive.ObjectIDFromHex(c.GetString("sessionId")); err == nil {
+				filter["_id"] = bson.M{"$ne": current}
+			}
+		}
+
+		res, err := db.Collection("sessions").DeleteMany(ctx, filter)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		writeAudit(ctx, db, "session.revoked_all", userOID, userOID, c.ClientIP(), "")
+
+		c.JSON(http.StatusOK, gin.H{"revoked": res.DeletedCount})
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestListMySessionsLocation(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	alice := insertUser(t, db, models.User{Username: "alice"}, "x")
+	token := insertSession(t, db, alice)
+
+	// The session was opened by a sign-in the access log placed in Lisbon
+	if _, err := db.Collection("sessions").UpdateOne(ctx,
+		bson.M{"userId": alice.UserID}, bson.M{"$set": bson.M{"requestId": "req-1"}},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Collection("login_events").InsertOne(ctx, models.LoginEvent{
+		Time: time.Now(), UserID: alice.UserID, SignIn: true, Success: true,
+		RequestID: "req-1", Country: "PT", City: "Lisbon",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/users/me/sessions", AuthRequired(db), ListMySessions(db))
+	w := doJSON(t, r, http.MethodGet, "/users/me/sessions", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	var views []sessionView
+	if err := json.Unmarshal(w.Body.Bytes(), &views); err != nil {
+		t.Fatal(err)
+	}
+	if len(views) != 1 || views[0].Country != "PT" || views[0].City != "Lisbon" {
+		t.Fatalf("sessions = %+v, want one in Lisbon, PT", views)
+	}
+}
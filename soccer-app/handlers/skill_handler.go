@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"soccer-app/migrations"
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// loadSkillCatalogue returns the active skills of group. Groups without a
+// catalogue fall back to models.DefaultSkills.
+func loadSkillCatalogue(ctx context.Context, db *mongo.Database, group string) ([]models.SkillDef, error) {
+	if group == "" {
+		group = models.DefaultGroup
+	}
+
+	cur, err := db.Collection("skills").Find(ctx,
+		bson.M{"group": group, "retiredAt": bson.M{"$exists": false}},
+		options.Find().SetSort(bson.M{"createdAt": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var defs []models.SkillDef
+	if err := cur.All(ctx, &defs); err != nil {
+		return nil, err
+	}
+	if len(defs) == 0 {
+		var n int64
+		if n, err = db.Collection("skills").CountDocuments(ctx, bson.M{"group": group}); err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return models.DefaultSkills, nil
+		}
+	}
+	return defs, nil
+}
+
+// validateSkills checks names against the group's catalogue and values
+// against each skill's range, returning the skills with normalized names.
+// A non-empty message means the input was rejected.
+func validateSkills(catalogue []models.SkillDef, in []models.Skill) ([]models.Skill, string) {
+	if len(in) == 0 {
+		return nil, "at least one skill is required"
+	}
+
+	defs := make(map[string]models.SkillDef, len(catalogue))
+	for _, d := range catalogue {
+		defs[d.Name] = d
+	}
+
+	seen := make(map[string]bool, len(in))
+	out := make([]models.Skill, 0, len(in))
+	for _, s := range in {
+		name := strings.ToLower(s.Name)
+
+		d, ok := defs[name]
+		if !ok {
+			return nil, "invalid skill: " + s.Name
+		}
+		if seen[name] {
+			return nil, "duplicate skill: " + s.Name
+		}
+		seen[name] = true
+
+		if s.Value < d.Min || s.Value > d.Max {
+			return nil, "skill " + name + " must be between " + strconv.Itoa(d.Min) + " and " + strconv.Itoa(d.Max)
+		}
+
+		out = append(out, models.Skill{Name: name, Value: s.Value})
+	}
+	return out, ""
+}
+
+// ListSkills returns a group's active skill catalogue (?group=, defaults to
+// the caller's group).
+func ListSkills(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group := strings.ToLower(strings.TrimSpace(c.Query("group")))
+		if group == "" {
+			user, ok := loadCurrentUser(c, db)
+			if !ok {
+				return
+			}
+			group = user.Group
+		}
+
+		defs, err := loadSkillCatalogue(context.Background(), db, group)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, defs)
+	}
+}
+
+// AddSkill adds a skill to a group's catalogue, or brings back a retired
+// one. With defaultValue set, users in the group get the skill at that
+// value. Admin only.
+func AddSkill(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		var req struct {
+			Group        string   `json:"group"`
+			Name         string   `json:"name"`
+			DisplayName  string   `json:"displayName"`
+			Min          int      `json:"min"`
+			Max          int      `json:"max"`
+			Weight       *float64 `json:"weight"`
+			DefaultValue int      `json:"defaultValue"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		group := models.NormalizeHandle(req.Group)
+		if group == "" {
+			group = models.DefaultGroup
+		}
+		if !models.ValidHandle(group) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group"})
+			return
+		}
+		name := models.NormalizeHandle(req.Name)
+		if !models.ValidHandle(name) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid skill name"})
+			return
+		}
+		if req.DisplayName == "" {
+			req.DisplayName = req.Name
+		}
+		if req.Min == 0 && req.Max == 0 {
+			req.Min, req.Max = 1, 10
+		}
+		if req.Max <= req.Min {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max must be greater than min"})
+			return
+		}
+		weight := 1.0
+		if req.Weight != nil {
+			weight = *req.Weight
+		}
+		if weight < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "weight cannot be negative"})
+			return
+		}
+		if req.DefaultValue != 0 && (req.DefaultValue < req.Min || req.DefaultValue > req.Max) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "defaultValue is out of range"})
+			return
+		}
+
+		// Adding to a group that still runs on the defaults starts its own
+		// catalogue, so the defaults must be copied in first.
+		if err := migrations.SeedSkillCatalogue(ctx, db, group); err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		var def models.SkillDef
+		err := db.Collection("skills").FindOneAndUpdate(
+			ctx,
+			bson.M{"group": group, "name": name},
+			bson.M{
+				"$set": bson.M{
+					"displayName": req.DisplayName,
+					"min":         req.Min,
+					"max":         req.Max,
+					"weight":      weight,
+				},
+				"$unset":       bson.M{"retiredAt": ""},
+				"$setOnInsert": bson.M{"createdAt": time.Now()},
+			},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&def)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		if req.DefaultValue != 0 {
+			if _, err := db.Collection("users").UpdateMany(ctx,
+				bson.M{"group": group, "skills.name": bson.M{"$ne": name}},
+				bson.M{"$push": bson.M{"skills": models.Skill{Name: name, Value: req.DefaultValue}}},
+			); err != nil {
+				internalError(c, "failed to migrate users", err)
+				return
+			}
+		}
+
+		actor, _ := currentUserID(c)
+		writeAudit(ctx, db, "skill.added", actor, primitive.NilObjectID, c.ClientIP(), group+"/"+name)
+
+		c.JSON(http.StatusOK, def)
+	}
+}
+
+// UpdateSkill changes a skill's display name, weight or range. Existing
+// user values are clamped into a narrowed range. Admin only.
+func UpdateSkill(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		skillOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid skill id"})
+			return
+		}
+
+		var req struct {
+			DisplayName *string  `json:"displayName"`
+			Min         *int     `json:"min"`
+			Max         *int     `json:"max"`
+			Weight      *float64 `json:"weight"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		var def models.SkillDef
+		err = db.Collection("skills").FindOne(ctx, bson.M{"_id": skillOID}).Decode(&def)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "skill not found"})
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		if req.DisplayName != nil {
+			def.DisplayName = *req.DisplayName
+		}
+		if req.Min != nil {
+			def.Min = *req.Min
+		}
+		if req.Max != nil {
+			def.Max = *req.Max
+		}
+		if req.Weight != nil {
+			def.Weight = *req.Weight
+		}
+		if def.Max <= def.Min {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max must be greater than min"})
+			return
+		}
+		if def.Weight < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "weight cannot be negative"})
+			return
+		}
+
+		if _, err := db.Collection("skills").UpdateOne(ctx,
+			bson.M{"_id": skillOID},
+			bson.M{"$set": bson.M{
+				"displayName": def.DisplayName,
+				"min":         def.Min,
+				"max":         def.Max,
+				"weight":      def.Weight,
+			}},
+		); err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		// Clamp stored values into the (possibly new) range
+		if _, err := db.Collection("users").UpdateMany(ctx,
+			bson.M{"group": def.Group, "skills.name": def.Name},
+			mongo.Pipeline{{{Key: "$set", Value: bson.M{"skills": bson.M{"$map": bson.M{
+				"input": "$skills",
+				"as":    "s",
+				"in": bson.M{"$cond": bson.A{
+					bson.M{"$eq": bson.A{"$$s.name", def.Name}},
+					bson.M{
+						"name":  "$$s.name",
+						"value": bson.M{"$min": bson.A{def.Max, bson.M{"$max": bson.A{def.Min, "$$s.value"}}}},
+					},
+					"$$s",
+				}},
+			}}}}}},
+		); err != nil {
+			internalError(c, "failed to migrate users", err)
+			return
+		}
+
+		actor, _ := currentUserID(c)
+		writeAudit(ctx, db, "skill.updated", actor, primitive.NilObjectID, c.ClientIP(), def.Group+"/"+def.Name)
+
+		c.JSON(http.StatusOK, def)
+	}
+}
+
+// retireClaimTTL is how long RetireSkill's claim on a skill holds off
+// other retirements in case the request dies half way.
+const retireClaimTTL = time.Minute
+
+// RetireSkill removes a skill from the catalogue and from every user in
+// the group. The group's last active skill can't be retired. Admin only.
+func RetireSkill(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		skillOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid skill id"})
+			return
+		}
+
+		// 1️⃣ Claim the skill; a claim left by a crashed request expires
+		now := time.Now()
+		var def models.SkillDef
+		err = db.Collection("skills").FindOneAndUpdate(ctx,
+			bson.M{
+				"_id":       skillOID,
+				"retiredAt": bson.M{"$exists": false},
+				"$or": bson.A{
+					bson.M{"retiringAt": bson.M{"$exists": false}},
+					bson.M{"retiringAt": bson.M{"$lt": now.Add(-retireClaimTTL)}},
+				},
+			},
+			bson.M{"$set": bson.M{"retiringAt": now}},
+		).Decode(&def)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "skill not found"})
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		// 2️⃣ A group needs at least one skill to rate players on. Skills
+		// claimed by a parallel retirement don't count, so two requests
+		// can't each retire one of the last two.
+		others, err := db.Collection("skills").CountDocuments(ctx, bson.M{
+			"group":      def.Group,
+			"_id":        bson.M{"$ne": skillOID},
+			"retiredAt":  bson.M{"$exists": false},
+			"retiringAt": bson.M{"$exists": false},
+		})
+		if err == nil && others == 0 {
+			_, err = db.Collection("skills").UpdateOne(ctx,
+				bson.M{"_id": skillOID},
+				bson.M{"$unset": bson.M{"retiringAt": ""}},
+			)
+			if err == nil {
+				c.JSON(http.StatusConflict, gin.H{"error": "cannot retire the last skill of a group"})
+				return
+			}
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		// 3️⃣ Retire it and take it off every user
+		if _, err := db.Collection("skills").UpdateOne(ctx,
+			bson.M{"_id": skillOID},
+			bson.M{
+				"$set":   bson.M{"retiredAt": now},
+				"$unset": bson.M{"retiringAt": ""},
+			},
+		); err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		if _, err := db.Collection("users").UpdateMany(ctx,
+			bson.M{"group": def.Group, "skills.name": def.Name},
+			bson.M{"$pull": bson.M{"skills": bson.M{"name": def.Name}}},
+		); err != nil {
+			internalError(c, "failed to migrate users", err)
+			return
+		}
+
+		actor, _ := currentUserID(c)
+		writeAudit(ctx, db, "skill.retired", actor, primitive.NilObjectID, c.ClientIP(), def.Group+"/"+def.Name)
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRetireSkillKeepsOne(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	var ids []string
+	for _, name := range []string{"speed", "passing"} {
+		res, err := db.Collection("skills").InsertOne(ctx, models.SkillDef{
+			Group: "tuesday", Name: name, DisplayName: name, Min: 1, Max: 10, Weight: 1, CreatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, res.InsertedID.(primitive.ObjectID).Hex())
+	}
+
+	r := gin.New()
+	r.DELETE("/admin/skills/:id", RetireSkill(db))
+
+	if w := doJSON(t, r, "DELETE", "/admin/skills/"+ids[0], "", nil); w.Code != http.StatusOK {
+		t.Fatalf("first skill: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if w := doJSON(t, r, "DELETE", "/admin/skills/"+ids[1], "", nil); w.Code != http.StatusConflict {
+		t.Fatalf("last skill: status = %d, want 409", w.Code)
+	}
+}
+
+func TestRetireLastTwoSkillsInParallel(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	var ids []string
+	for _, name := range []string{"speed", "passing"} {
+		res, err := db.Collection("skills").InsertOne(ctx, models.SkillDef{
+			Group: "tuesday", Name: name, DisplayName: name, Min: 1, Max: 10, Weight: 1, CreatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, res.InsertedID.(primitive.ObjectID).Hex())
+	}
+
+	r := gin.New()
+	r.DELETE("/admin/skills/:id", RetireSkill(db))
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			doJSON(t, r, "DELETE", "/admin/skills/"+id, "", nil)
+		}()
+	}
+	wg.Wait()
+
+	active, err := db.Collection("skills").CountDocuments(ctx, bson.M{"group": "tuesday", "retiredAt": bson.M{"$exists": false}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active == 0 {
+		t.Fatal("both skills were retired")
+	}
+}
+
+func TestAddSkillValidatesGroup(t *testing.T) {
+	db := testDB(t)
+
+	r := gin.New()
+	r.POST("/admin/skills", AddSkill(db))
+
+	w := doJSON(t, r, "POST", "/admin/skills", "", gin.H{"group": "no such group!", "name": "heading"})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", w.Code, w.Body)
+	}
+}
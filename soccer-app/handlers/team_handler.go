@@ -4,9 +4,12 @@ import (
 	"context"
 	"math/rand"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -14,6 +17,13 @@ import (
 	"soccer-app/models"
 )
 
+// teamGenerations counts GenerateTeams calls by outcome: "generated",
+// "existing" when teams were already stored, or "no_votes".
+var teamGenerations = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "soccer_team_generations_total",
+	Help: "GenerateTeams calls, by outcome.",
+}, []string{"outcome"})
+
 type PlayerRating struct {
 	Name     string         `json:"name"`
 	Skills   []models.Skill `json:"skills,omitempty"`
@@ -65,6 +75,7 @@ func GenerateTeams(db *mongo.Database) gin.HandlerFunc {
 
 		if err == nil {
 			// ✅ Teams already generated → just return DB state
+			teamGenerations.WithLabelValues("existing").Inc()
 			c.JSON(http.StatusOK, gin.H{
 				"yesCount":    existing.YesCount,
 				"teamA":       existing.TeamA,
@@ -83,7 +94,7 @@ func GenerateTeams(db *mongo.Database) gin.HandlerFunc {
 			"attending": true,
 		})
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			internalError(c, "db error", err)
 			return
 		}
 		defer cur.Close(ctx)
@@ -92,11 +103,12 @@ func GenerateTeams(db *mongo.Database) gin.HandlerFunc {
 			UserID primitive.ObjectID `bson:"userId"`
 		}
 		if err := cur.All(ctx, &votes); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "decode error"})
+			internalError(c, "decode error", err)
 			return
 		}
 
 		if len(votes) == 0 {
+			teamGenerations.WithLabelValues("no_votes").Inc()
 			c.JSON(http.StatusOK, gin.H{
 				"yesCount": 0,
 				"teamA":    []TeamPlayer{},
@@ -115,14 +127,14 @@ func GenerateTeams(db *mongo.Database) gin.HandlerFunc {
 			"_id": bson.M{"$in": userIDs},
 		})
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load users"})
+			internalError(c, "failed to load users", err)
 			return
 		}
 		defer userCur.Close(ctx)
 
 		var users []models.User
 		if err := userCur.All(ctx, &users); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "user decode error"})
+			internalError(c, "user decode error", err)
 			return
 		}
 
@@ -138,21 +150,19 @@ func GenerateTeams(db *mongo.Database) gin.HandlerFunc {
 			})
 		}
 
-		// 6️⃣ Shuffle ONCE
-		rand.Seed(time.Now().UnixNano())
-		rand.Shuffle(len(players), func(i, j int) {
-			players[i], players[j] = players[j], players[i]
-		})
-
-		var teamA, teamB []models.User
-		for i, p := range players {
-			if i%2 == 0 {
-				teamA = append(teamA, p)
-			} else {
-				teamB = append(teamB, p)
-			}
+		// 6️⃣ Weighted score from peer-calibrated skills
+		profiles, catalogues, err := calibratedProfiles(ctx, db, users)
+		if err != nil {
+			internalError(c, "failed to load skills", err)
+			return
+		}
+		scores := make(map[primitive.ObjectID]float64, len(users))
+		for _, u := range users {
+			scores[u.UserID] = models.CalibratedScore(profiles[u.UserID], catalogues[u.Group])
 		}
 
+		teamA, teamB := balanceTeams(players, scores)
+
 		// 7️⃣ PERSIST TEAMS (MOST IMPORTANT STEP)
 		teamsDoc := models.PollTeams{
 			PollID:      pollOID,
@@ -165,10 +175,12 @@ func GenerateTeams(db *mongo.Database) gin.HandlerFunc {
 
 		_, err = db.Collection("teams").InsertOne(ctx, teamsDoc)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save teams"})
+			internalError(c, "failed to save teams", err)
 			return
 		}
 
+		teamGenerations.WithLabelValues("generated").Inc()
+
 		// 8️⃣ Return DB-backed response
 		c.JSON(http.StatusOK, gin.H{
 			"yesCount":    teamsDoc.YesCount,
@@ -224,7 +236,7 @@ func GetTeams(db *mongo.Database) gin.HandlerFunc {
 		}
 
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load teams"})
+			internalError(c, "failed to load teams", err)
 			return
 		}
 
@@ -241,3 +253,29 @@ func GetTeams(db *mongo.Database) gin.HandlerFunc {
 		})
 	}
 }
+
+// balanceTeams splits players into two teams of (nearly) equal size with
+// similar total skill scores. Players are shuffled first so equal scores
+// don't always land on the same side.
+func balanceTeams(players []models.User, scores map[primitive.ObjectID]float64) (teamA, teamB []models.User) {
+	rand.Shuffle(len(players), func(i, j int) {
+		players[i], players[j] = players[j], players[i]
+	})
+	sort.SliceStable(players, func(i, j int) bool {
+		return scores[players[i].UserID] > scores[players[j].UserID]
+	})
+
+	half := (len(players) + 1) / 2
+	var totalA, totalB float64
+	for _, p := range players {
+		score := scores[p.UserID]
+		if len(teamB) >= half || (len(teamA) < half && totalA <= totalB) {
+			teamA = append(teamA, p)
+			totalA += score
+		} else {
+			teamB = append(teamB, p)
+			totalB += score
+		}
+	}
+	return teamA, teamB
+}
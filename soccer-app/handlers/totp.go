@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	totpIssuer  = "Soccer App"
+	totpPeriod  = 30
+	totpDigits  = 6
+	totpSkew    = 1 // accept one step either side for clock drift
+	recoveryLen = 10
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func newTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b32.EncodeToString(b), nil
+}
+
+// totpAt computes the RFC 6238 code (HMAC-SHA1) for a time step.
+func totpAt(secret string, step int64) (string, error) {
+	key, err := b32.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	off := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, bin%1000000), nil
+}
+
+// matchTOTP returns the time step code matches, or -1. Steps at or before
+// lastStep are refused so a code can't be replayed.
+func matchTOTP(secret, code string, lastStep int64) int64 {
+	code = strings.TrimSpace(code)
+	now := time.Now().Unix() / totpPeriod
+	for d := int64(-totpSkew); d <= totpSkew; d++ {
+		step := now + d
+		if step <= lastStep {
+			continue
+		}
+		want, err := totpAt(secret, step)
+		if err != nil {
+			return -1
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step
+		}
+	}
+	return -1
+}
+
+func totpURI(secret, account string) string {
+	label := url.PathEscape(totpIssuer + ":" + account)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", totpIssuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// newRecoveryCodes returns raw codes for the user and their hashes for storage.
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryLen; i++ {
+		code, err := newOneTimeCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, HashSecret(code))
+	}
+	return codes, hashes, nil
+}
+
+// verifySecondFactor checks a TOTP code or, failing that, a recovery code
+// (which is consumed). It persists the replay guard / used code.
+func verifySecondFactor(ctx context.Context, db *mongo.Database, user models.User, totpCode, recoveryCode string) (bool, error) {
+	users := db.Collection("users")
+
+	if totpCode != "" {
+		step := matchTOTP(user.TOTPSecret, totpCode, user.TOTPLastStep)
+		if step < 0 {
+			return false, nil
+		}
+		res, err := users.UpdateOne(ctx,
+			bson.M{"_id": user.UserID, "totpLastStep": user.TOTPLastStep},
+			bson.M{"$set": bson.M{"totpLastStep": step}},
+		)
+		if err != nil {
+			return false, err
+		}
+		// Lost a race with a concurrent login using the same code
+		return res.ModifiedCount == 1, nil
+	}
+
+	if recoveryCode != "" {
+		res, err := users.UpdateOne(ctx,
+			bson.M{"_id": user.UserID, "recoveryCodes": HashSecret(normalizeOneTimeCode(recoveryCode))},
+			bson.M{"$pull": bson.M{"recoveryCodes": HashSecret(normalizeOneTimeCode(recoveryCode))}},
+		)
+		if err != nil {
+			return false, err
+		}
+		if res.ModifiedCount == 1 {
+			writeAudit(ctx, db, "totp.recovery_code_used", user.UserID, user.UserID, "", "")
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func loadCurrentUser(c *gin.Context, db *mongo.Database) (models.User, bool) {
+	var user models.User
+
+	userOID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return user, false
+	}
+
+	err := db.Collection("users").FindOne(context.Background(), bson.M{"_id": userOID}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return user, false
+	}
+	if err != nil {
+		internalError(c, "db error", err)
+		return user, false
+	}
+	return user, true
+}
+
+// BeginTOTPEnrolment generates a new TOTP secret for the authenticated user
+// and returns the otpauth:// provisioning URI to show as a QR code. The
+// secret only takes effect once confirmed with ConfirmTOTPEnrolment.
+func BeginTOTPEnrolment(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := loadCurrentUser(c, db)
+		if !ok {
+			return
+		}
+		if user.TOTPEnabled {
+			c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication already enabled"})
+			return
+		}
+
+		secret, err := newTOTPSecret()
+		if err != nil {
+			internalError(c, "failed to generate secret", err)
+			return
+		}
+
+		if _, err := db.Collection("users").UpdateOne(
+			context.Background(),
+			bson.M{"_id": user.UserID},
+			bson.M{"$set": bson.M{"totpSecret": secret}},
+		); err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"secret":          secret,
+			"provisioningUri": totpURI(secret, user.Username),
+		})
+	}
+}
+
+// ConfirmTOTPEnrolment enables TOTP once the user proves their
+// authenticator works, and returns one-time recovery codes.
+func ConfirmTOTPEnrolment(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+			return
+		}
+
+		user, ok := loadCurrentUser(c, db)
+		if !ok {
+			return
+		}
+		if user.TOTPEnabled {
+			c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication already enabled"})
+			return
+		}
+		if user.TOTPSecret == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start enrolment first"})
+			return
+		}
+
+		step := matchTOTP(user.TOTPSecret, req.Code, 0)
+		if step < 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+			return
+		}
+
+		codes, hashes, err := newRecoveryCodes()
+		if err != nil {
+			internalError(c, "failed to generate recovery codes", err)
+			return
+		}
+
+		if _, err := db.Collection("users").UpdateOne(
+			ctx,
+			bson.M{"_id": user.UserID},
+			bson.M{"$set": bson.M{
+				"totpEnabled":   true,
+				"totpLastStep":  step,
+				"recoveryCodes": hashes,
+			}},
+		); err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		writeAudit(ctx, db, "totp.enabled", user.UserID, user.UserID, c.ClientIP(), "")
+
+		c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+	}
+}
+
+// DisableTOTP turns off two-factor authentication after checking a current
+// code. Admins can't disable it because it is mandatory for them.
+func DisableTOTP(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		var req struct {
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recoveryCode"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		user, ok := loadCurrentUser(c, db)
+		if !ok {
+			return
+		}
+		if !user.TOTPEnabled {
+			c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication is not enabled"})
+			return
+		}
+		if user.Role == models.RoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "two-factor authentication is mandatory for admins"})
+			return
+		}
+
+		valid, err := verifySecondFactor(ctx, db, user, req.Code, req.RecoveryCode)
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+		if !valid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+			return
+		}
+
+		if _, err := db.Collection("users").UpdateOne(
+			ctx,
+			bson.M{"_id": user.UserID},
+			bson.M{
+				"$set":   bson.M{"totpEnabled": false},
+				"$unset": bson.M{"totpSecret": "", "totpLastStep": "", "recoveryCodes": ""},
+			},
+		); err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		writeAudit(ctx, db, "totp.disabled", user.UserID, user.UserID, c.ClientIP(), "")
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
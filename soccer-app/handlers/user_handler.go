@@ -0,0 +1,450 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// publicUser is what other players may see about a user.
+func publicUser(u models.User) gin.H {
+	return gin.H{
+		"userId":    u.UserID.Hex(),
+		"username":  u.Username,
+		"firstName": u.FirstName,
+		"lastName":  u.LastName,
+		"group":     u.Group,
+		"position":  u.Position,
+		"skills":    u.Skills,
+	}
+}
+
+func findUser(c *gin.Context, db *mongo.Database, id primitive.ObjectID) (models.User, bool) {
+	var user models.User
+	err := db.Collection("users").FindOne(context.Background(), bson.M{"_id": id}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return user, false
+	}
+	if err != nil {
+		internalError(c, "db error", err)
+		return user, false
+	}
+	return user, true
+}
+
+// GetMe returns the authenticated user's full profile.
+func GetMe(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := loadCurrentUser(c, db)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+// GetUser returns a user's profile. Organisers see everything, other users
+// only the public fields.
+func GetUser(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return
+		}
+
+		user, ok := findUser(c, db, userOID)
+		if !ok {
+			return
+		}
+
+		if models.HasRole(c.GetString("role"), models.RoleOrganiser) {
+			c.JSON(http.StatusOK, user)
+			return
+		}
+		c.JSON(http.StatusOK, publicUser(user))
+	}
+}
+
+// UpdateUser edits another user's profile directly, skipping skill review.
+// Admin only.
+func UpdateUser(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		userOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return
+		}
+
+		var req struct {
+			FirstName *string        `json:"firstName"`
+			LastName  *string        `json:"lastName"`
+			Email     *string        `json:"email"`
+			Group     *string        `json:"group"`
+			Position  *string        `json:"position"`
+			Skills    []models.Skill `json:"skills"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		set, unset, msg := profileChanges(req.FirstName, req.LastName, req.Email)
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		if req.Group != nil {
+			set["group"] = strings.ToLower(strings.TrimSpace(*req.Group))
+		}
+		if req.Position != nil {
+			set["position"] = *req.Position
+		}
+		if req.Skills != nil {
+			current, ok := findUser(c, db, userOID)
+			if !ok {
+				return
+			}
+			group := current.Group
+			if g, ok := set["group"].(string); ok {
+				group = g
+			}
+			catalogue, err := loadSkillCatalogue(ctx, db, group)
+			if err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+			skills, msg := validateSkills(catalogue, req.Skills)
+			if msg != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+				return
+			}
+			set["skills"] = skills
+		}
+
+		update := bson.M{}
+		if len(set) > 0 {
+			update["$set"] = set
+		}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+		if len(update) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
+			return
+		}
+
+		var user models.User
+		err = db.Collection("users").FindOneAndUpdate(
+			ctx,
+			bson.M{"_id": userOID},
+			update,
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&user)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "email already taken"})
+			return
+		}
+		if err != nil {
+			internalError(c, "db error", err)
+			return
+		}
+
+		actor, _ := currentUserID(c)
+		writeAudit(ctx, db, "user.updated", actor, userOID, c.ClientIP(), "")
+
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+// profileChanges validates the name/email fields shared by self-service
+// and admin profile updates.
+func profileChanges(firstName, lastName, email *string) (set, unset bson.M, msg string) {
+	set, unset = bson.M{}, bson.M{}
+
+	if firstName != nil {
+		if strings.TrimSpace(*firstName) == "" {
+			return nil, nil, "firstName cannot be empty"
+		}
+		set["firstName"] = strings.TrimSpace(*firstName)
+	}
+	if lastName != nil {
+		if strings.TrimSpace(*lastName) == "" {
+			return nil, nil, "lastName cannot be empty"
+		}
+		set["lastName"] = strings.TrimSpace(*lastName)
+	}
+	if email != nil {
+		e := strings.ToLower(strings.TrimSpace(*email))
+		switch {
+		case e == "":
+			unset["email"] = ""
+		case !strings.Contains(e, "@"):
+			return nil, nil, "invalid email"
+		default:
+			set["email"] = e
+		}
+	}
+	return set, unset, ""
+}
+
+// DeleteMe deletes the authenticated user's account. The body must repeat
+// the username as {"confirm": "<username>"}.
+func DeleteMe(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Confirm string `json:"confirm"`
+		}
+		_ = c.ShouldBindJSON(&req)
+
+		user, ok := loadCurrentUser(c, db)
+		if !ok {
+			return
+		}
+		if models.NormalizeHandle(req.Confirm) != user.Username {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "confirm must match your username"})
+			return
+		}
+		if user.Role == models.RoleAdmin && !notLastAdmin(c, db, user.UserID) {
+			return
+		}
+
+		if err := deleteUser(context.Background(), db, user); err != nil {
+			internalError(c, "failed to delete account", err)
+			return
+		}
+
+		writeAudit(context.Background(), db, "user.deleted", user.UserID, user.UserID, c.ClientIP(), "self")
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// DeleteUser deletes another user's account. Admin only.
+func DeleteUser(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return
+		}
+
+		actor, _ := currentUserID(c)
+		if actor == userOID {
+			c.JSON(http.StatusConflict, gin.H{"error": "use DELETE /users/me to delete your own account"})
+			return
+		}
+
+		user, ok := findUser(c, db, userOID)
+		if !ok {
+			return
+		}
+
+		if err := deleteUser(context.Background(), db, user); err != nil {
+			internalError(c, "failed to delete account", err)
+			return
+		}
+
+		writeAudit(context.Background(), db, "user.deleted", actor, userOID, c.ClientIP(), "admin")
+
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// deleteUser removes the account and everything that can sign in as it,
+// and anonymises the user's votes, team entries and peer ratings. Those
+// keep a placeholder ID so per-poll counts and calibrated skills still
+// add up without pointing at a user that no longer exists.
+//
+// Every step can be repeated, and the placeholder is stored on the user
+// before anything moves, so a deletion that fails half way is finished by
+// running it again; the user document goes last. ResumeUserDeletions does
+// that on startup.
+func deleteUser(ctx context.Context, db *mongo.Database, user models.User) error {
+	// 0️⃣ Pick the placeholder once, keeping one from an earlier attempt
+	anonID := user.DeletingAs
+	if anonID.IsZero() {
+		if _, err := db.Collection("users").UpdateOne(ctx,
+			bson.M{"_id": user.UserID, "deletingAs": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"deletingAs": primitive.NewObjectID()}},
+		); err != nil {
+			return err
+		}
+		err := db.Collection("users").FindOne(ctx, bson.M{"_id": user.UserID}).Decode(&user)
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		anonID = user.DeletingAs
+	}
+
+	// 1️⃣ Votes
+	if _, err := db.Collection("votes").UpdateMany(ctx,
+		bson.M{"userId": user.UserID},
+		bson.M{
+			"$set": bson.M{
+				"userId":    anonID,
+				"username":  "",
+				"firstName": "Deleted",
+				"lastName":  "player",
+			},
+		},
+	); err != nil {
+		return err
+	}
+
+	// 2️⃣ Team entries (embedded users in teamA / teamB)
+	for _, team := range []string{"teamA", "teamB"} {
+		if _, err := db.Collection("teams").UpdateMany(ctx,
+			bson.M{team + "._id": user.UserID},
+			bson.M{"$set": bson.M{
+				team + ".$[p]._id":       anonID,
+				team + ".$[p].username":  "",
+				team + ".$[p].firstName": "Deleted",
+				team + ".$[p].lastName":  "player",
+			}},
+			options.Update().SetArrayFilters(options.ArrayFilters{
+				Filters: []interface{}{bson.M{"p._id": user.UserID}},
+			}),
+		); err != nil {
+			return err
+		}
+	}
+
+	// 3️⃣ Peer ratings given and received
+	for _, field := range []string{"raterId", "rateeId"} {
+		if _, err := db.Collection("peer_ratings").UpdateMany(ctx,
+			bson.M{field: user.UserID},
+			bson.M{"$set": bson.M{field: anonID}},
+		); err != nil {
+			return err
+		}
+	}
+
+	// 4️⃣ Credentials and pending requests
+	for coll, filter := range map[string]bson.M{
+		"sessions":          {"userId": user.UserID},
+		"reset_codes":       {"userId": user.UserID},
+		"skill_changes":     {"userId": user.UserID},
+		"webauthn_sessions": {"userId": user.UserID},
+		"api_keys":          {"ownerId": user.UserID},
+		"login_attempts":    {"_id": accountKey(user.Username)},
+		"login_events":      {"userId": user.UserID},
+		"login_alerts":      {"userId": user.UserID},
+	} {
+		if _, err := db.Collection(coll).DeleteMany(ctx, filter); err != nil {
+			return err
+		}
+	}
+
+	// 5️⃣ The account itself
+	_, err := db.Collection("users").DeleteOne(ctx, bson.M{"_id": user.UserID})
+	return err
+}
+
+// ResumeUserDeletions finishes deleting accounts whose deletion was
+// interrupted. It runs on startup.
+func ResumeUserDeletions(ctx context.Context, db *mongo.Database) error {
+	cur, err := db.Collection("users").Find(ctx, bson.M{"deletingAs": bson.M{"$exists": true}})
+	if err != nil {
+		return err
+	}
+	var users []models.User
+	if err := cur.All(ctx, &users); err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		if err := deleteUser(ctx, db, u); err != nil {
+			return fmt.Errorf("%s: %w", u.UserID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// ExportMyData returns everything stored about the authenticated user as
+// a single JSON document.
+func ExportMyData(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+
+		user, ok := loadCurrentUser(c, db)
+		if !ok {
+			return
+		}
+
+		export := gin.H{
+			"exportedAt": time.Now(),
+			"user":       user,
+		}
+
+		var (
+			votes         []models.Vote
+			sessions      []models.Session
+			apiKeys       []models.APIKey
+			skillChanges  []models.SkillChange
+			audit         []models.AuditEntry
+			peerRatings   []models.PeerRating
+			ratingsOfUser []models.PeerRating
+			loginEvents   []models.LoginEvent
+			loginAlerts   []models.LoginAlert
+		)
+		for _, q := range []struct {
+			key    string
+			coll   string
+			filter bson.M
+			out    interface{}
+			hide   bson.M // fields that belong to someone else
+		}{
+			{"votes", "votes", bson.M{"userId": user.UserID}, &votes, nil},
+			{"sessions", "sessions", bson.M{"userId": user.UserID}, &sessions, nil},
+			{"apiKeys", "api_keys", bson.M{"ownerId": user.UserID}, &apiKeys, nil},
+			{"skillChanges", "skill_changes", bson.M{"userId": user.UserID}, &skillChanges, nil},
+			{"peerRatingsGiven", "peer_ratings", bson.M{"raterId": user.UserID}, &peerRatings, nil},
+			{"peerRatingsReceived", "peer_ratings", bson.M{"rateeId": user.UserID}, &ratingsOfUser, bson.M{"raterId": 0}},
+			{"loginEvents", "login_events", bson.M{"userId": user.UserID}, &loginEvents, nil},
+			{"loginAlerts", "login_alerts", bson.M{"userId": user.UserID}, &loginAlerts, nil},
+			{"auditLog", "audit_log", bson.M{"$or": bson.A{
+				bson.M{"actorId": user.UserID},
+				bson.M{"targetId": user.UserID},
+			}}, &audit, nil},
+		} {
+			opts := options.Find()
+			if q.hide != nil {
+				opts.SetProjection(q.hide)
+			}
+			cur, err := db.Collection(q.coll).Find(ctx, q.filter, opts)
+			if err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+			if err := cur.All(ctx, q.out); err != nil {
+				internalError(c, "decode error", err)
+				return
+			}
+			export[q.key] = q.out
+		}
+
+		c.Header("Content-Disposition", `attachment; filename="`+user.Username+`-export.json"`)
+		c.JSON(http.StatusOK, export)
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResumeUserDeletions(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	anonID := primitive.NewObjectID()
+
+	// A deletion that got as far as moving one of two votes
+	dora := insertUser(t, db, models.User{Username: "dora", DeletingAs: anonID}, "x")
+	bob := insertUser(t, db, models.User{Username: "bob"}, "x")
+	pollID := primitive.NewObjectID()
+	if _, err := db.Collection("votes").InsertMany(ctx, []any{
+		models.Vote{PollID: pollID, UserID: anonID, Attending: true},
+		models.Vote{PollID: primitive.NewObjectID(), UserID: dora.UserID, Attending: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Collection("peer_ratings").InsertMany(ctx, []any{
+		models.PeerRating{PollID: pollID, RaterID: dora.UserID, RateeID: bob.UserID, CreatedAt: time.Now()},
+		models.PeerRating{PollID: pollID, RaterID: bob.UserID, RateeID: dora.UserID, CreatedAt: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ResumeUserDeletions(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func(coll string, filter bson.M) int64 {
+		t.Helper()
+		n, err := db.Collection(coll).CountDocuments(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := count("users", bson.M{"_id": dora.UserID}); n != 0 {
+		t.Fatal("user was not deleted")
+	}
+	if n := count("votes", bson.M{"userId": anonID}); n != 2 {
+		t.Fatalf("%d votes on the placeholder, want 2", n)
+	}
+	if n := count("peer_ratings", bson.M{"$or": bson.A{bson.M{"raterId": anonID}, bson.M{"rateeId": anonID}}}); n != 2 {
+		t.Fatalf("%d peer ratings on the placeholder, want 2", n)
+	}
+	if n := count("peer_ratings", bson.M{"$or": bson.A{bson.M{"raterId": dora.UserID}, bson.M{"rateeId": dora.UserID}}}); n != 0 {
+		t.Fatalf("%d peer ratings still point at the user", n)
+	}
+}
+
+func TestDeleteMeKeepsLastAdmin(t *testing.T) {
+	db := testDB(t)
+	admin := insertUser(t, db, models.User{Username: "ada", Role: models.RoleAdmin}, "ada-secret")
+
+	r := gin.New()
+	r.DELETE("/users/me", AuthRequired(db), DeleteMe(db))
+
+	w := doJSON(t, r, http.MethodDelete, "/users/me", insertSession(t, db, admin), gin.H{"confirm": "ada"})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409: %s", w.Code, w.Body)
+	}
+}
+
+func TestDeletingUserCannotSignIn(t *testing.T) {
+	db := testDB(t)
+	dora := insertUser(t, db, models.User{Username: "dora"}, "dora-secret")
+	token := insertSession(t, db, dora)
+	if _, err := db.Collection("users").UpdateOne(context.Background(),
+		bson.M{"_id": dora.UserID}, bson.M{"$set": bson.M{"deletingAs": primitive.NewObjectID()}},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.POST("/login", LoginUser(db))
+	r.GET("/me", AuthRequired(db), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	if w := doJSON(t, r, http.MethodPost, "/login", "", gin.H{"username": "dora", "secret": "dora-secret"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("login: status = %d, want 401: %s", w.Code, w.Body)
+	}
+	if w := doJSON(t, r, http.MethodGet, "/me", token, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("existing session: status = %d, want 401: %s", w.Code, w.Body)
+	}
+}
+
+func TestExportIncludesRatingsReceived(t *testing.T) {
+	db := testDB(t)
+	alice := insertUser(t, db, models.User{Username: "alice"}, "alice-secret")
+	bob := insertUser(t, db, models.User{Username: "bob"}, "bob-secret")
+	if _, err := db.Collection("peer_ratings").InsertOne(context.Background(), models.PeerRating{
+		PollID: primitive.NewObjectID(), RaterID: bob.UserID, RateeID: alice.UserID, CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/users/me/export", AuthRequired(db), ExportMyData(db))
+	w := doJSON(t, r, http.MethodGet, "/users/me/export", insertSession(t, db, alice), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	var export struct {
+		Received []models.PeerRating `json:"peerRatingsReceived"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &export); err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Received) != 1 {
+		t.Fatalf("%d ratings received, want 1", len(export.Received))
+	}
+	// Who gave the rating stays private
+	if export.Received[0].RaterID == bob.UserID {
+		t.Fatal("export reveals the rater")
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
 	"time"
 
@@ -19,8 +20,7 @@ func SubmitVote(db *mongo.Database) gin.HandlerFunc {
 		var req struct {
 			UserID    primitive.ObjectID `json:"userId"`
 			PollID    primitive.ObjectID `json:"pollId"`
-			FirstName string             `json:"firstName"`
-			LastName  string             `json:"lastName"`
+			Username  string             `json:"username"`
 			Secret    string             `json:"secret"`
 			Rating    int                `json:"rating"`
 			Attending bool               `json:"attending"`
@@ -31,28 +31,92 @@ func SubmitVote(db *mongo.Database) gin.HandlerFunc {
 			return
 		}
 
-		// 🔐 Verify user + secret
+		ctx := context.Background()
+
+		// 🔐 Work out who is voting
+		var filter bson.M
+		var acctKey, clientKey string
+		switch {
+		case isAPIKeyRequest(c) && c.GetString("apiKeyGroup") != "":
+			// Group keys (e.g. the chat bot) vote on behalf of a member of
+			// the key's group
+			filter = bson.M{
+				"username": models.NormalizeHandle(req.Username),
+				"group":    c.GetString("apiKeyGroup"),
+			}
+		case isAPIKeyRequest(c):
+			// Personal keys only vote as their owner
+			ownerOID, _ := currentUserID(c)
+			filter = bson.M{"_id": ownerOID}
+		case c.GetString("userId") != "":
+			// Signed-in user votes for themselves
+			userOID, _ := currentUserID(c)
+			filter = bson.M{"_id": userOID}
+		default:
+			// Username and secret, throttled like LoginUser
+			acctKey = accountKey(req.Username)
+			clientKey = ipKey(c.ClientIP())
+			wait, err := lockedFor(ctx, db, acctKey, clientKey)
+			if err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+			if wait > 0 {
+				respondLocked(c, wait)
+				return
+			}
+			filter = bson.M{"username": models.NormalizeHandle(req.Username)}
+		}
+
 		var user models.User
-		err := db.Collection("users").FindOne(
-			context.Background(),
-			bson.M{
-				"firstName":  req.FirstName,
-				"lastName":   req.LastName,
-				"secretHash": HashSecret(req.Secret),
-			},
-		).Decode(&user)
+		err := db.Collection("users").FindOne(ctx, filter).Decode(&user)
+		if err != nil && err != mongo.ErrNoDocuments {
+			internalError(c, "db error", err)
+			return
+		}
 
+		if acctKey != "" {
+			// Unknown users and wrong secrets look the same
+			if err == mongo.ErrNoDocuments || req.Secret == "" ||
+				subtle.ConstantTimeCompare([]byte(user.SecretHash), []byte(HashSecret(req.Secret))) != 1 {
+				if err := recordFailures(ctx, db, acctKey, clientKey); err != nil {
+					internalError(c, "db error", err)
+					return
+				}
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+				return
+			}
+			if err := clearFailures(ctx, db, acctKey); err != nil {
+				internalError(c, "db error", err)
+				return
+			}
+
+			// A secret alone must not get round a second factor or a hold
+			if user.ReverifyRequired {
+				respondSessionError(c, errReverifyRequired)
+				return
+			}
+			if user.TOTPEnabled {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "this account uses two-factor sign-in, sign in to vote"})
+				return
+			}
+		}
+
+		if err == mongo.ErrNoDocuments && isAPIKeyRequest(c) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
 		if err == mongo.ErrNoDocuments {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 			return
 		}
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		if isAPIKeyRequest(c) && req.Username != "" && models.NormalizeHandle(req.Username) != user.Username {
+			c.JSON(http.StatusForbidden, gin.H{"error": "this key can only vote as its owner"})
 			return
 		}
 
 		// 🔁 Upsert vote + ADD userId
-		filter := bson.M{
+		filter = bson.M{
 			"pollId": req.PollID,
 			"userId": user.UserID, // 🔑 ensures one vote per user per poll
 		}
@@ -61,8 +125,9 @@ func SubmitVote(db *mongo.Database) gin.HandlerFunc {
 			"$set": bson.M{
 				"pollId":    req.PollID,
 				"userId":    user.UserID, // ✅ NEW FIELD
-				"firstName": req.FirstName,
-				"lastName":  req.LastName,
+				"username":  user.Username,
+				"firstName": user.FirstName,
+				"lastName":  user.LastName,
 				"rating":    req.Rating,
 				"attending": req.Attending,
 				"updatedAt": time.Now(),
@@ -75,12 +140,12 @@ func SubmitVote(db *mongo.Database) gin.HandlerFunc {
 		opts := options.Update().SetUpsert(true)
 
 		if _, err := db.Collection("votes").UpdateOne(
-			context.Background(),
+			ctx,
 			filter,
 			update,
 			opts,
 		); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "vote failed"})
+			internalError(c, "vote failed", err)
 			return
 		}
 
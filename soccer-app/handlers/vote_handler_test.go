@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"soccer-app/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func insertAPIKey(t *testing.T, db *mongo.Database, owner primitive.ObjectID, group string, scopes ...string) string {
+	t.Helper()
+	raw, prefix, err := newAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Collection("api_keys").InsertOne(context.Background(), models.APIKey{
+		Name:      "test",
+		Prefix:    prefix,
+		KeyHash:   HashSecret(raw),
+		OwnerID:   owner,
+		Group:     group,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestAPIKeyVoteIdentity(t *testing.T) {
+	db := testDB(t)
+	alice := insertUser(t, db, models.User{Username: "alice", Group: "tuesday"}, "alice-secret")
+	bob := insertUser(t, db, models.User{Username: "bob", Group: "tuesday"}, "bob-secret")
+	carol := insertUser(t, db, models.User{Username: "carol", Group: "sunday"}, "carol-secret")
+	org := insertUser(t, db, models.User{Username: "olive", Group: "tuesday", Role: models.RoleOrganiser}, "olive-secret")
+
+	personal := insertAPIKey(t, db, alice.UserID, "", models.ScopeVotesWrite)
+	groupKey := insertAPIKey(t, db, org.UserID, "tuesday", models.ScopeVotesWrite)
+
+	r := gin.New()
+	r.POST("/votes", OptionalAuth(db, models.ScopeVotesWrite), SubmitVote(db))
+	pollID := primitive.NewObjectID()
+
+	cases := []struct {
+		name     string
+		key      string
+		username string
+		want     int
+		voter    primitive.ObjectID
+	}{
+		{"personal key as someone else", personal, "bob", http.StatusForbidden, bob.UserID},
+		{"personal key as owner", personal, "alice", http.StatusOK, alice.UserID},
+		{"personal key without username", personal, "", http.StatusOK, alice.UserID},
+		{"group key for member", groupKey, "bob", http.StatusOK, bob.UserID},
+		{"group key for another group", groupKey, "carol", http.StatusNotFound, carol.UserID},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := doJSON(t, r, http.MethodPost, "/votes", tc.key, gin.H{
+				"pollId":    pollID,
+				"username":  tc.username,
+				"attending": true,
+			})
+			if w.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tc.want, w.Body)
+			}
+
+			n, err := db.Collection("votes").CountDocuments(context.Background(), bson.M{"pollId": pollID, "userId": tc.voter})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if voted := n > 0; voted != (tc.want == http.StatusOK) {
+				t.Fatalf("vote stored = %v after status %d", voted, w.Code)
+			}
+		})
+	}
+}
+
+func TestCreateAPIKeyWriteScopeNeedsOrganiser(t *testing.T) {
+	db := testDB(t)
+	player := insertUser(t, db, models.User{Username: "pat"}, "pat-secret")
+
+	r := gin.New()
+	r.POST("/keys", func(c *gin.Context) {
+		setIdentity(c, player)
+	}, CreateAPIKey(db))
+
+	w := doJSON(t, r, http.MethodPost, "/keys", "", gin.H{"name": "bot", "scopes": []string{models.ScopeVotesWrite}})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("write scope: status = %d, want 403: %s", w.Code, w.Body)
+	}
+	w = doJSON(t, r, http.MethodPost, "/keys", "", gin.H{"name": "bot", "scopes": []string{models.ScopePollsRead}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("read scope: status = %d, want 201: %s", w.Code, w.Body)
+	}
+}
+
+func TestSecretVoteFallback(t *testing.T) {
+	db := testDB(t)
+	insertUser(t, db, models.User{Username: "alice"}, "alice-secret")
+	insertUser(t, db, models.User{Username: "tina", TOTPEnabled: true}, "tina-secret")
+	insertUser(t, db, models.User{Username: "hal", ReverifyRequired: true}, "hal-secret")
+
+	r := gin.New()
+	r.POST("/votes", OptionalAuth(db, models.ScopeVotesWrite), SubmitVote(db))
+	pollID := primitive.NewObjectID()
+
+	cases := []struct {
+		name, username, secret string
+		want                   int
+	}{
+		{"right secret", "alice", "alice-secret", http.StatusOK},
+		{"two-factor account", "tina", "tina-secret", http.StatusUnauthorized},
+		{"account on hold", "hal", "hal-secret", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := doJSON(t, r, http.MethodPost, "/votes", "", gin.H{
+				"pollId": pollID, "username": tc.username, "secret": tc.secret, "attending": true,
+			})
+			if w.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tc.want, w.Body)
+			}
+		})
+	}
+
+	// Wrong secrets lock the account like LoginUser does
+	for i := 0; i < accountFreeAttempts+1; i++ {
+		doJSON(t, r, http.MethodPost, "/votes", "", gin.H{"pollId": pollID, "username": "alice", "secret": "guess"})
+	}
+	w := doJSON(t, r, http.MethodPost, "/votes", "", gin.H{"pollId": pollID, "username": "alice", "secret": "alice-secret"})
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("after guesses: status = %d, want 429: %s", w.Code, w.Body)
+	}
+}
+
+func TestGroupKeyOwnership(t *testing.T) {
+	db := testDB(t)
+	org := insertUser(t, db, models.User{Username: "olive", Group: "tuesday", Role: models.RoleOrganiser}, "olive-secret")
+
+	r := gin.New()
+	r.POST("/keys", func(c *gin.Context) {
+		setIdentity(c, org)
+	}, CreateAPIKey(db))
+
+	cases := []struct {
+		group string
+		want  int
+	}{
+		{"Tuesday", http.StatusCreated},
+		{"sunday", http.StatusForbidden},
+		{"not a group!", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		w := doJSON(t, r, http.MethodPost, "/keys", "", gin.H{"name": "bot", "group": tc.group, "scopes": []string{models.ScopePollsRead}})
+		if w.Code != tc.want {
+			t.Fatalf("group %q: status = %d, want %d: %s", tc.group, w.Code, tc.want, w.Body)
+		}
+	}
+
+	// Demoting the owner disables their group keys
+	groupKey := insertAPIKey(t, db, org.UserID, "tuesday", models.ScopePollsRead)
+	authed := gin.New()
+	authed.GET("/polls", AuthRequired(db, models.ScopePollsRead), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	if w := doJSON(t, authed, http.MethodGet, "/polls", groupKey, nil); w.Code != http.StatusOK {
+		t.Fatalf("organiser key: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if _, err := db.Collection("users").UpdateOne(context.Background(),
+		bson.M{"_id": org.UserID}, bson.M{"$set": bson.M{"role": models.RolePlayer}},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if w := doJSON(t, authed, http.MethodGet, "/polls", groupKey, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("demoted owner's key: status = %d, want 401: %s", w.Code, w.Body)
+	}
+}
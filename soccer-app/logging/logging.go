@@ -0,0 +1,135 @@
+// Package logging sets up the server's JSON logger and the gin middleware
+// that tags every request with an ID and logs it.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"soccer-app/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// Incoming IDs longer than this are replaced rather than trusted.
+const maxRequestIDLen = 64
+
+type ctxKey struct{}
+
+// Setup installs a JSON logger writing to w as the slog default and
+// routes the standard log package through it.
+func Setup(w io.Writer, cfg config.Log) *slog.Logger {
+	logger := slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: cfg.Level}))
+	slog.SetDefault(logger)
+	return logger
+}
+
+// FromContext returns the request-scoped logger stored by RequestID, or
+// the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// From returns the logger for a gin request.
+func From(c *gin.Context) *slog.Logger {
+	return FromContext(c.Request.Context())
+}
+
+// RequestID reuses a sane incoming X-Request-ID or makes a new one, echoes
+// it in the response and attaches a logger carrying it to the request
+// context. It is stored in the gin context as "requestId".
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set("requestId", id)
+		c.Header(RequestIDHeader, id)
+
+		logger := slog.Default().With("requestId", id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxKey{}, logger))
+
+		c.Next()
+	}
+}
+
+// AccessLog logs one line per request once it has been handled. Server
+// errors log at error level, client errors at warn.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", route,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latencyMs", float64(time.Since(start).Microseconds()) / 1000,
+			"bytes", c.Writer.Size(),
+			"ip", c.ClientIP(),
+		}
+		if userID := c.GetString("userId"); userID != "" {
+			attrs = append(attrs, "userId", userID)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		From(c).Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Recovery turns a panic into a logged 500 instead of a dropped connection.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		From(c).Error("panic", "panic", err, "route", c.FullPath())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
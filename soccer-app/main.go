@@ -1,46 +1,95 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
-	"net"
+	"io"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"soccer-app/config"
+	"soccer-app/geo"
 	"soccer-app/handlers"
-	geo "soccer-app/handlers"
+	"soccer-app/logging"
+	"soccer-app/metrics"
+	"soccer-app/migrations"
+	"soccer-app/models"
+	"soccer-app/ratelimit"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/gin-gonic/gin/binding"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type LoginEvent struct {
-	FirstName string    `json:"firstName,omitempty"`
-	LastName  string    `json:"lastName,omitempty"`
-	Time      time.Time `json:"time"`
-	UserID    string    `json:"userId,omitempty"`
-	IP        string    `json:"ip"`
-	Method    string    `json:"method"`
-	Path      string    `json:"path"`
-	UserAgent string    `json:"userAgent"`
-	Referer   string    `json:"referer,omitempty"`
-	Country   string    `json:"country,omitempty"`
-	City      string    `json:"city,omitempty"`
-	ISP       string    `json:"isp,omitempty"`
-	Lat       string    `json:"lat,omitempty"`
-	Lng       string    `json:"lng,omitempty"`
-}
-
 func main() {
-	db := config.MustMongo()
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "config:", err)
+		os.Exit(2)
+	}
+
+	logging.Setup(os.Stdout, cfg.Log)
+
+	db, err := config.ConnectMongo(context.Background(), cfg.Mongo,
+		options.Client().SetMonitor(metrics.MongoMonitor()))
+	if err != nil {
+		fatal("database unavailable", err)
+	}
+
+	if err := migrations.Run(context.Background(), db); err != nil {
+		fatal("migrations failed", err)
+	}
+
+	// Bootstrap an admin so roles can be granted on a fresh deployment
+	if err := handlers.EnsureAdmin(db, cfg.AdminUsername); err != nil {
+		slog.Error("bootstrap admin failed", "error", err)
+	}
+
+	// Finish account deletions a crash or outage interrupted
+	if err := handlers.ResumeUserDeletions(context.Background(), db); err != nil {
+		slog.Error("resuming user deletions failed", "error", err)
+	}
+
+	geoProvider, err := geo.New(cfg.Geo)
+	if err != nil {
+		fatal("geo config invalid", err)
+	}
+
+	// Login events are geo-enriched and written in the background
+	detector := handlers.NewLoginDetector(db, cfg.LoginAlerts)
+	loginEvents := handlers.NewLoginEventQueue(db, cfg.AccessLog, geoProvider, detector.Check)
+
+	loc := cfg.Server.Location()
+	prometheus.MustRegister(loginEvents, handlers.NewPollCollector(db, loc))
+
+	r := gin.New()
+	r.Use(logging.RequestID(), logging.AccessLog(), logging.Recovery(), metrics.Middleware())
 
-	r := gin.Default()
+	// Probes, ahead of CORS and rate limiting
+	var draining atomic.Bool
+	r.GET("/healthz", handlers.Healthz())
+	r.GET("/readyz", handlers.Readyz(db, &draining))
+	r.GET("/metrics", metrics.Handler(cfg.Server.MetricsToken))
 
 	// CORS middleware
 	corsCfg := cors.DefaultConfig()
-	corsCfg.AllowAllOrigins = true
+	if cfg.CORS.AllowAll() {
+		corsCfg.AllowAllOrigins = true
+	} else {
+		corsCfg.AllowOrigins = cfg.CORS.Origins
+	}
 	corsCfg.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
 	corsCfg.AllowHeaders = []string{
 		"Origin",
@@ -49,15 +98,20 @@ func main() {
 		"Authorization",
 		"X-Requested-With",
 		"ngrok-skip-browser-warning",
+		logging.RequestIDHeader,
 	}
-	corsCfg.AllowCredentials = true
-	corsCfg.ExposeHeaders = []string{"Content-Length", "Content-Type"}
+	corsCfg.AllowCredentials = cfg.CORS.AllowCredentials
+	corsCfg.ExposeHeaders = append([]string{"Content-Length", "Content-Type", logging.RequestIDHeader}, ratelimit.Headers...)
 	r.Use(cors.New(corsCfg))
 
-	r.Use(RateLimitMiddleware(100)) // 100 requests per minute
+	limits := ratelimit.New(cfg.RateLimit, db)
+	r.Use(ratelimit.Middleware(limits, ratelimit.Policy{Name: "default", Limit: cfg.RateLimit.Default, Key: ratelimit.ByIP}))
+	loginLimit := ratelimit.Middleware(limits, ratelimit.Policy{Name: "login", Limit: cfg.RateLimit.Login, Key: ratelimit.ByIP})
+	voteLimit := ratelimit.Middleware(limits, ratelimit.Policy{Name: "votes", Limit: cfg.RateLimit.Votes, Key: ratelimit.ByUser})
 
-	// 100 requests per minute
-	r.SetTrustedProxies(nil) // trust all (OK for dev)
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		fatal("trusted proxies invalid", err)
+	}
 
 	// ✅ Serve static files
 	r.Static("/static", "./static")
@@ -87,138 +141,168 @@ func main() {
 	// ✅ API v1 routes (REGISTER ONCE)
 	api := r.Group("/api/v1")
 	{
+		auth := handlers.AuthRequired(db)
+		organiser := handlers.RequireRole(models.RoleOrganiser)
+		admin := handlers.RequireRole(models.RoleAdmin)
+		accessLog := handlers.RecordLoginEvents(loginEvents, cfg.AccessLog)
+
 		// players
-		api.GET("/players", handlers.GetPlayers(db))
-		api.POST("/players", handlers.CreatePlayer(db))
+		api.GET("/players", handlers.AuthRequired(db, models.ScopePlayersRead), handlers.GetPlayers(db))
+		api.GET("/players/:id", handlers.AuthRequired(db, models.ScopePlayersRead), handlers.GetPlayer(db))
+		api.POST("/players", handlers.AuthRequired(db, models.ScopePlayersWrite), organiser, handlers.CreatePlayer(db))
+		api.PUT("/players/:id", handlers.AuthRequired(db, models.ScopePlayersWrite), organiser, handlers.UpdatePlayer(db))
+		api.DELETE("/players/:id", handlers.AuthRequired(db, models.ScopePlayersWrite), organiser, handlers.DeletePlayer(db))
 
 		// polls
-		api.POST("/polls", handlers.CreatePoll(db))
-		api.GET("/polls/current", handlers.GetCurrentPoll(db))
-		api.POST("/polls/:id/teams", handlers.GenerateTeams(db))
-		api.GET("/polls/:id/teams", handlers.GetTeams(db))
-		api.POST("/polls/:id/teams/move", handlers.MovePlayer(db))
+		api.POST("/polls", handlers.AuthRequired(db, models.ScopePollsWrite), organiser, handlers.CreatePoll(db, loc))
+		api.GET("/polls/current", handlers.OptionalAuth(db, models.ScopePollsRead), handlers.GetCurrentPoll(db, loc))
+		api.POST("/polls/:id/teams", handlers.AuthRequired(db, models.ScopeTeamsWrite), organiser, handlers.GenerateTeams(db))
+		api.GET("/polls/:id/teams", handlers.OptionalAuth(db, models.ScopePollsRead), handlers.GetTeams(db))
+		api.POST("/polls/:id/teams/move", handlers.AuthRequired(db, models.ScopeTeamsWrite), organiser, handlers.MovePlayer(db))
 
 		// auth & voting
-		api.POST("/register", handlers.RegisterUser(db))
-		api.POST("/votes", handlers.SubmitVote(db))
-
-		api.POST("/login", handlers.LoginUser(db))
+		api.POST("/register", accessLog, handlers.RegisterUser(db))
+		api.POST("/votes", handlers.OptionalAuth(db, models.ScopeVotesWrite), voteLimit, handlers.SubmitVote(db))
+
+		api.POST("/login", loginLimit, accessLog, handlers.LoginUser(db))
+		api.POST("/auth/mfa/verify", loginLimit, accessLog, handlers.CompleteMFAChallenge(db))
+
+		// API keys
+		api.POST("/api-keys", auth, handlers.CreateAPIKey(db))
+		api.GET("/api-keys", auth, handlers.ListAPIKeys(db))
+		api.DELETE("/api-keys/:id", auth, handlers.RevokeAPIKey(db))
+
+		// profile
+		api.GET("/users/me", auth, handlers.GetMe(db))
+		api.PUT("/users/me", auth, handlers.UpdateMyProfile(db))
+		api.DELETE("/users/me", auth, handlers.DeleteMe(db))
+		api.GET("/users/me/export", auth, handlers.ExportMyData(db))
+		api.GET("/users/me/sessions", auth, handlers.ListMySessions(db))
+		api.DELETE("/users/me/sessions", auth, handlers.RevokeMySessions(db))
+		api.DELETE("/users/me/sessions/:id", auth, handlers.RevokeMySession(db))
+		api.GET("/users/:id", auth, handlers.GetUser(db))
+		api.PUT("/users/:id", auth, admin, handlers.UpdateUser(db))
+		api.DELETE("/users/:id", auth, admin, handlers.DeleteUser(db))
+		api.POST("/users/me/totp", auth, handlers.BeginTOTPEnrolment(db))
+		api.POST("/users/me/totp/verify", auth, handlers.ConfirmTOTPEnrolment(db))
+		api.DELETE("/users/me/totp", auth, handlers.DisableTOTP(db))
+		api.GET("/skill-changes", auth, organiser, handlers.ListSkillChanges(db))
+		api.POST("/skill-changes/:id/approve", auth, organiser, handlers.ApproveSkillChange(db))
+		api.POST("/skill-changes/:id/reject", auth, organiser, handlers.RejectSkillChange(db))
+
+		// skill catalogue
+		api.GET("/skills", auth, handlers.ListSkills(db))
+		api.POST("/admin/skills", auth, admin, handlers.AddSkill(db))
+		api.PUT("/admin/skills/:id", auth, admin, handlers.UpdateSkill(db))
+		api.DELETE("/admin/skills/:id", auth, admin, handlers.RetireSkill(db))
+
+		// peer ratings
+		api.POST("/polls/:id/ratings", auth, handlers.SubmitPeerRatings(db))
+		api.GET("/users/:id/skills", auth, handlers.GetCalibratedSkills(db))
+
+		// invites & groups
+		api.POST("/invites", auth, organiser, handlers.CreateInvite(db))
+		api.GET("/invites", auth, organiser, handlers.ListInvites(db))
+		api.DELETE("/invites/:id", auth, organiser, handlers.RevokeInvite(db))
+		api.PUT("/admin/groups/:name", auth, admin, handlers.UpdateGroup(db))
+
+		// secret reset
+		api.POST("/users/:id/reset-codes", auth, organiser, handlers.IssueResetCode(db))
+		api.POST("/reset-secret", loginLimit, accessLog, handlers.ResetSecret(db))
+
+		// passkeys
+		wa, err := handlers.NewWebAuthn(cfg.WebAuthn)
+		if err != nil {
+			fatal("webauthn config invalid", err)
+		}
+		api.GET("/users/me/passkeys", auth, handlers.ListPasskeys(db))
+		api.POST("/users/me/passkeys/register/begin", auth, handlers.BeginPasskeyRegistration(db, wa))
+		api.POST("/users/me/passkeys/register/finish", auth, handlers.FinishPasskeyRegistration(db, wa))
+		api.DELETE("/users/me/passkeys/:id", auth, handlers.DeletePasskey(db))
+		api.POST("/auth/passkey/login/begin", handlers.BeginPasskeyLogin(db, wa))
+		api.POST("/auth/passkey/login/finish", loginLimit, accessLog, handlers.FinishPasskeyLogin(db, wa))
+
+		// OIDC sign-in (optional)
+		if cfg.OIDC.Enabled() {
+			provider, err := handlers.NewOIDCProvider(context.Background(), cfg.OIDC)
+			if err != nil {
+				fatal("oidc discovery failed", err)
+			}
+			api.GET("/auth/oidc/login", handlers.OIDCLogin(db, provider))
+			api.GET("/auth/oidc/callback", accessLog, handlers.OIDCCallback(db, provider))
+			api.POST("/auth/oidc/link", auth, handlers.OIDCLink(db, provider))
+			api.DELETE("/auth/oidc/link", auth, handlers.UnlinkOIDC(db, provider))
+		}
 
+		// admin
+		api.PUT("/admin/users/:id/role", auth, admin, handlers.GrantRole(db))
+		api.DELETE("/admin/users/:id/role", auth, admin, handlers.RevokeRole(db))
+		api.DELETE("/admin/users/:id/lockout", auth, admin, handlers.UnlockUser(db))
+		api.GET("/admin/player-conflicts", auth, admin, handlers.ListPlayerMergeConflicts(db))
+		api.GET("/admin/login-events", auth, admin, handlers.ListLoginEvents(db))
+		api.GET("/admin/login-events/stats", auth, admin, handlers.LoginEventQueueStats(loginEvents))
+
+		// suspicious sign-ins
+		api.GET("/login-alerts", auth, organiser, handlers.ListLoginAlerts(db))
+		api.POST("/login-alerts/:id/resolve", auth, organiser, handlers.ResolveLoginAlert(db))
+		api.GET("/users/me/login-alerts", auth, handlers.ListMyLoginAlerts(db))
 	}
 
-	log.Println("Server running on :8080")
-	log.Fatal(r.Run(":8080"))
-}
-
-type RegisterBody struct {
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-}
-
-func WhoLoggedIn() gin.HandlerFunc {
-	return func(c *gin.Context) {
-
-		// Skip CORS preflight
-		if c.Request.Method == http.MethodOptions {
-			c.Next()
-			return
+	srv := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+	}
+	go func() {
+		slog.Info("server running", "addr", cfg.Server.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("server stopped", err)
 		}
+	}()
 
-		ip := c.ClientIP()
-		ua := c.GetHeader("User-Agent")
-		ref := c.GetHeader("Referer")
-
-		fmt.Println("Client IP:", ip)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	<-ctx.Done()
+	stop()
 
-		userID := ""
-		if uid, ok := c.Get("userId"); ok {
-			userID, _ = uid.(string)
-		}
+	slog.Info("shutting down")
+	draining.Store(true)
+	shutdown(cfg.Server.ShutdownTimeout, srv, loginEvents, geoProvider, limits, db)
+}
 
-		firstName, lastName := "", ""
+// shutdown stops taking requests and lets in-flight ones finish, then
+// writes out queued login events, which still need the geo provider and
+// Mongo, before closing those. All of it has to fit in timeout.
+func shutdown(timeout time.Duration, srv *http.Server, q *handlers.LoginEventQueue, provider geo.Provider, limits ratelimit.Store, db *mongo.Database) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-		// Only parse body where it actually exists
-		if c.FullPath() == "/api/v1/register" {
-			var rb RegisterBody
-			if err := c.ShouldBindBodyWith(&rb, binding.JSON); err == nil {
-				firstName = rb.FirstName
-				lastName = rb.LastName
-			}
-		}
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("http shutdown incomplete", "error", err)
+	}
 
-		event := LoginEvent{
-			Time:      time.Now().UTC(),
-			UserID:    userID,
-			FirstName: firstName,
-			LastName:  lastName,
-			IP:        ip,
-			Method:    c.Request.Method,
-			Path:      c.FullPath(),
-			UserAgent: ua,
-			Referer:   ref,
-		}
+	if err := q.Close(ctx); err != nil {
+		slog.Error("login event drain failed", "error", err)
+	}
+	st := q.Stats()
+	slog.Info("login events drained", "written", st.Written, "dropped", st.Dropped, "failed", st.Failed)
 
-		// Geo only for public IPs
-		if !isPrivateIP(ip) {
-			if geoInfo, err := geo.LookupGeo(ip); err == nil {
-				event.Country = geoInfo.Country
-				event.City = geoInfo.City
-				event.ISP = geoInfo.ISP
-				event.Lat = geoInfo.Lat
-				event.Lng = geoInfo.Lng
-			}
+	if c, ok := provider.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			slog.Error("geo provider close failed", "error", err)
 		}
-
-		logLoginEvent(event)
-		c.Next()
 	}
-}
-
-func logLoginEvent(e LoginEvent) {
-	// In production → write to DB
-	// or structured logger (zap / logrus)
-	println(
-		e.Method,
-		e.FirstName,
-		e.LastName,
-		"logged in at",
-		e.Time.Format(time.RFC3339),
-		e.UserID,
-		e.IP,
-		e.Path,
-		e.UserAgent,
-		e.Referer,
-		"Country:",
-		e.Country,
-		"CITY:",
-		e.City,
-		"ISP:",
-		e.ISP,
-		"LAT:",
-		e.Lat,
-		"LNG:",
-		e.Lng,
-	)
-}
+	if c, ok := limits.(io.Closer); ok {
+		c.Close()
+	}
 
-// RateLimitMiddleware limits the number of requests per minute
-func RateLimitMiddleware(maxRequestsPerMinute int) gin.HandlerFunc {
-	visitors := make(map[string]int)
-	return func(c *gin.Context) {
-		clientIP := c.ClientIP()
-		visitors[clientIP]++
-		if visitors[clientIP] > maxRequestsPerMinute {
-			c.AbortWithStatusJSON(429, gin.H{"error": "too many requests"})
-			return
-		}
-		c.Next()
+	if err := db.Client().Disconnect(ctx); err != nil {
+		slog.Error("mongo disconnect failed", "error", err)
 	}
+	slog.Info("shutdown complete")
 }
 
-func isPrivateIP(ip string) bool {
-	parsed := net.ParseIP(ip)
-	if parsed == nil {
-		return true
-	}
-	return parsed.IsPrivate() || parsed.IsLoopback()
+// fatal logs err and exits; startup can't continue past it.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
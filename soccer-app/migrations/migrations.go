@@ -0,0 +1,518 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"soccer-app/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Run applies all data migrations and index definitions. Every step is
+// idempotent so it is safe to call on each startup.
+func Run(ctx context.Context, db *mongo.Database) error {
+	if err := mergePlayers(ctx, db); err != nil {
+		return fmt.Errorf("merge players: %w", err)
+	}
+	if err := assignHandles(ctx, db); err != nil {
+		return fmt.Errorf("assign handles: %w", err)
+	}
+	if err := assignDefaultGroup(ctx, db); err != nil {
+		return fmt.Errorf("assign default group: %w", err)
+	}
+	if err := backfillAvgRating(ctx, db); err != nil {
+		return fmt.Errorf("backfill avg rating: %w", err)
+	}
+	if err := seedSkillCatalogues(ctx, db); err != nil {
+		return fmt.Errorf("seed skill catalogues: %w", err)
+	}
+	if err := ensureUserIndexes(ctx, db); err != nil {
+		return fmt.Errorf("user indexes: %w", err)
+	}
+	if err := ensureGroupIndexes(ctx, db); err != nil {
+		return fmt.Errorf("group indexes: %w", err)
+	}
+	if err := ensureAPIKeyIndexes(ctx, db); err != nil {
+		return fmt.Errorf("api key indexes: %w", err)
+	}
+	if err := ensureSessionIndexes(ctx, db); err != nil {
+		return fmt.Errorf("session indexes: %w", err)
+	}
+	if err := ensurePeerRatingIndexes(ctx, db); err != nil {
+		return fmt.Errorf("peer rating indexes: %w", err)
+	}
+	if err := ensureLoginEventIndexes(ctx, db); err != nil {
+		return fmt.Errorf("login event indexes: %w", err)
+	}
+	if err := ensureSkillChangeIndexes(ctx, db); err != nil {
+		return fmt.Errorf("skill change indexes: %w", err)
+	}
+	if err := ensureExpiryIndexes(ctx, db); err != nil {
+		return fmt.Errorf("expiry indexes: %w", err)
+	}
+	return nil
+}
+
+// mergePlayers moves documents from the old players collection into users.
+// A player whose name matches exactly one user fills in that user's
+// missing position and rating; one with no match becomes a new user with
+// the same ID. Players matching several users are left alone and recorded
+// in player_merge_conflicts for an admin to sort out.
+func mergePlayers(ctx context.Context, db *mongo.Database) error {
+	cur, err := db.Collection("players").Find(ctx, bson.M{"mergedInto": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	var players []struct {
+		ID        primitive.ObjectID `bson:"_id"`
+		Name      string             `bson:"name"`
+		Position  string             `bson:"position"`
+		AvgRating float64            `bson:"avgRating"`
+	}
+	if err := cur.All(ctx, &players); err != nil {
+		return err
+	}
+	if len(players) == 0 {
+		return nil
+	}
+
+	users := db.Collection("users")
+	ucur, err := users.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	var all []models.User
+	if err := ucur.All(ctx, &all); err != nil {
+		return err
+	}
+	byName := make(map[string][]models.User)
+	for _, u := range all {
+		byName[strings.ToLower(u.FullName())] = append(byName[strings.ToLower(u.FullName())], u)
+	}
+
+	merged, created, conflicts := 0, 0, 0
+	for _, p := range players {
+		first, last := models.SplitName(p.Name)
+		if first == "" {
+			slog.Warn("migrations: player has no name, skipping", "playerId", p.ID.Hex())
+			continue
+		}
+		matches := byName[strings.ToLower(strings.TrimSpace(first+" "+last))]
+
+		var target primitive.ObjectID
+		switch len(matches) {
+		case 0:
+			u := models.User{
+				UserID:    p.ID,
+				FirstName: first,
+				LastName:  last,
+				Position:  p.Position,
+				AvgRating: p.AvgRating,
+				Role:      models.RolePlayer,
+				CreatedAt: p.ID.Timestamp(),
+			}
+			if _, err := users.InsertOne(ctx, u); err != nil && !mongo.IsDuplicateKeyError(err) {
+				return err
+			}
+			target = p.ID
+			created++
+		case 1:
+			u := matches[0]
+			set := bson.M{}
+			if u.Position == "" && p.Position != "" {
+				set["position"] = p.Position
+			}
+			if u.AvgRating == 0 && p.AvgRating != 0 {
+				set["avgRating"] = p.AvgRating
+			}
+			if len(set) > 0 {
+				if _, err := users.UpdateOne(ctx, bson.M{"_id": u.UserID}, bson.M{"$set": set}); err != nil {
+					return err
+				}
+			}
+			target = u.UserID
+			merged++
+		default:
+			candidates := make([]primitive.ObjectID, 0, len(matches))
+			for _, u := range matches {
+				candidates = append(candidates, u.UserID)
+			}
+			if _, err := db.Collection("player_merge_conflicts").ReplaceOne(ctx,
+				bson.M{"_id": p.ID},
+				models.PlayerMergeConflict{
+					ID:         p.ID,
+					Name:       p.Name,
+					Position:   p.Position,
+					AvgRating:  p.AvgRating,
+					Candidates: candidates,
+					ReportedAt: time.Now(),
+				},
+				options.Replace().SetUpsert(true),
+			); err != nil {
+				return err
+			}
+			slog.Warn("migrations: player matches several users, not merged", "name", p.Name, "matches", len(matches))
+			conflicts++
+			continue
+		}
+
+		if _, err := db.Collection("players").UpdateOne(ctx,
+			bson.M{"_id": p.ID},
+			bson.M{"$set": bson.M{"mergedInto": target}},
+		); err != nil {
+			return err
+		}
+		if _, err := db.Collection("player_merge_conflicts").DeleteOne(ctx, bson.M{"_id": p.ID}); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("migrations: players merged", "existingUsers", merged, "newUsers", created, "conflicts", conflicts)
+	return nil
+}
+
+// assignHandles gives every user without a username one derived from their
+// name, and flags users that share a name with someone else.
+func assignHandles(ctx context.Context, db *mongo.Database) error {
+	users := db.Collection("users")
+
+	cur, err := users.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	var all []models.User
+	if err := cur.All(ctx, &all); err != nil {
+		return err
+	}
+
+	taken := make(map[string]bool, len(all))
+	byName := make(map[string][]models.User)
+	for _, u := range all {
+		if u.Username != "" {
+			taken[u.Username] = true
+		}
+		name := strings.ToLower(strings.TrimSpace(u.FirstName)) + "|" + strings.ToLower(strings.TrimSpace(u.LastName))
+		byName[name] = append(byName[name], u)
+	}
+
+	assigned := 0
+	for _, u := range all {
+		if u.Username != "" {
+			continue
+		}
+
+		base := models.HandleFromName(u.FirstName, u.LastName)
+		handle := base
+		for n := 2; taken[handle]; n++ {
+			handle = fmt.Sprintf("%s%d", base, n)
+		}
+		taken[handle] = true
+
+		if _, err := users.UpdateOne(ctx,
+			bson.M{"_id": u.UserID, "username": bson.M{"$in": bson.A{nil, ""}}},
+			bson.M{"$set": bson.M{"username": handle}},
+		); err != nil {
+			return err
+		}
+		assigned++
+	}
+
+	flagged := 0
+	for name, group := range byName {
+		if len(group) < 2 {
+			continue
+		}
+		newly := 0
+		for _, u := range group {
+			if u.PossibleDuplicate {
+				continue
+			}
+			if _, err := users.UpdateOne(ctx,
+				bson.M{"_id": u.UserID},
+				bson.M{"$set": bson.M{"possibleDuplicate": true}},
+			); err != nil {
+				return err
+			}
+			newly++
+		}
+		if newly > 0 {
+			slog.Warn("migrations: users share a name", "name", name, "count", len(group))
+		}
+		flagged += newly
+	}
+
+	if assigned > 0 || flagged > 0 {
+		slog.Info("migrations: handles assigned", "assigned", assigned, "possibleDuplicates", flagged)
+	}
+	return nil
+}
+
+// assignDefaultGroup puts users registered before groups existed into
+// the default group.
+func assignDefaultGroup(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("users").UpdateMany(ctx,
+		bson.M{"group": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"$set": bson.M{"group": models.DefaultGroup}},
+	)
+	return err
+}
+
+// backfillAvgRating stores an explicit 0 rating on users that have none,
+// so sorting and paging by rating sees every user.
+func backfillAvgRating(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("users").UpdateMany(ctx,
+		bson.M{"avgRating": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"avgRating": 0.0}},
+	)
+	return err
+}
+
+// seedSkillCatalogues gives every group in use its own copy of the default
+// skills, which is what the hard-coded list used to allow.
+func seedSkillCatalogues(ctx context.Context, db *mongo.Database) error {
+	if _, err := db.Collection("skills").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "group", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetName("group_name_unique").SetUnique(true),
+	}); err != nil {
+		return err
+	}
+
+	groups, err := db.Collection("users").Distinct(ctx, "group", bson.M{})
+	if err != nil {
+		return err
+	}
+	named, err := db.Collection("groups").Distinct(ctx, "name", bson.M{})
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, g := range append(append(groups, named...), models.DefaultGroup) {
+		name, ok := g.(string)
+		if !ok || name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if err := SeedSkillCatalogue(ctx, db, name); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// SeedSkillCatalogue copies models.DefaultSkills into group's catalogue
+// if it has none yet.
+func SeedSkillCatalogue(ctx context.Context, db *mongo.Database, group string) error {
+	n, err := db.Collection("skills").CountDocuments(ctx, bson.M{"group": group})
+	if err != nil || n > 0 {
+		return err
+	}
+
+	now := time.Now()
+	for i, d := range models.DefaultSkills {
+		_, err := db.Collection("skills").UpdateOne(ctx,
+			bson.M{"group": group, "name": d.Name},
+			bson.M{"$setOnInsert": bson.M{
+				"displayName": d.DisplayName,
+				"min":         d.Min,
+				"max":         d.Max,
+				"weight":      d.Weight,
+				"createdAt":   now.Add(time.Duration(i) * time.Millisecond), // keeps list order
+			}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ensureUserIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("users").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "firstName", Value: 1}, {Key: "lastName", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("name_sort"),
+		},
+		{
+			Keys:    bson.D{{Key: "avgRating", Value: -1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("avgRating_sort"),
+		},
+		{
+			Keys: bson.D{{Key: "username", Value: 1}},
+			Options: options.Index().
+				SetName("username_unique").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"username": bson.M{"$type": "string"}}),
+		},
+		{
+			Keys: bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().
+				SetName("email_unique").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"email": bson.M{"$type": "string"}}),
+		},
+		{
+			Keys: bson.D{{Key: "passkeys.credential.id", Value: 1}},
+			Options: options.Index().
+				SetName("passkey_unique").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"passkeys.credential.id": bson.M{"$exists": true}}),
+		},
+		{
+			Keys: bson.D{{Key: "identities.issuer", Value: 1}, {Key: "identities.subject", Value: 1}},
+			Options: options.Index().
+				SetName("identity_unique").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"identities.subject": bson.M{"$exists": true}}),
+		},
+	})
+	return err
+}
+
+func ensureGroupIndexes(ctx context.Context, db *mongo.Database) error {
+	if _, err := db.Collection("groups").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetName("name_unique").SetUnique(true),
+	}); err != nil {
+		return err
+	}
+	_, err := db.Collection("invites").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "codeHash", Value: 1}},
+		Options: options.Index().SetName("codeHash_unique").SetUnique(true),
+	})
+	return err
+}
+
+func ensureAPIKeyIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("api_keys").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "keyHash", Value: 1}},
+			Options: options.Index().SetName("keyHash_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "ownerId", Value: 1}},
+			Options: options.Index().SetName("ownerId"),
+		},
+	})
+	return err
+}
+
+func ensureSessionIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("sessions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+			Options: options.Index().SetName("tokenHash"),
+		},
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "lastSeenAt", Value: -1}},
+			Options: options.Index().SetName("userId_lastSeenAt"),
+		},
+	})
+	return err
+}
+
+func ensurePeerRatingIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("peer_ratings").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "pollId", Value: 1}, {Key: "raterId", Value: 1}, {Key: "rateeId", Value: 1}},
+			Options: options.Index().SetName("poll_rater_ratee_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "rateeId", Value: 1}, {Key: "createdAt", Value: -1}},
+			Options: options.Index().SetName("ratee_createdAt"),
+		},
+	})
+	return err
+}
+
+func ensureLoginEventIndexes(ctx context.Context, db *mongo.Database) error {
+	idx := make([]mongo.IndexModel, 0, 4)
+	for _, field := range []string{"", "userId", "ip", "country"} {
+		keys := bson.D{{Key: "time", Value: -1}, {Key: "_id", Value: -1}}
+		name := "time"
+		if field != "" {
+			keys = append(bson.D{{Key: field, Value: 1}}, keys...)
+			name = field + "_time"
+		}
+		idx = append(idx, mongo.IndexModel{Keys: keys, Options: options.Index().SetName(name)})
+	}
+	if _, err := db.Collection("login_events").Indexes().CreateMany(ctx, idx); err != nil {
+		return err
+	}
+	_, err := db.Collection("login_alerts").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}},
+			Options: options.Index().SetName("userId_createdAt"),
+		},
+		{
+			Keys:    bson.D{{Key: "resolvedAt", Value: 1}, {Key: "createdAt", Value: -1}},
+			Options: options.Index().SetName("resolvedAt_createdAt"),
+		},
+	})
+	return err
+}
+
+// ensureSkillChangeIndexes allows one pending skill change per user. Older
+// duplicates left by concurrent requests are rejected first, keeping the
+// newest.
+func ensureSkillChangeIndexes(ctx context.Context, db *mongo.Database) error {
+	coll := db.Collection("skill_changes")
+	cur, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": "PENDING"}}},
+		{{Key: "$sort", Value: bson.D{{Key: "requestedAt", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": "$userId",
+			"ids": bson.M{"$push": "$_id"},
+		}}},
+		{{Key: "$match", Value: bson.M{"ids.1": bson.M{"$exists": true}}}},
+	})
+	if err != nil {
+		return err
+	}
+	var dups []struct {
+		IDs []primitive.ObjectID `bson:"ids"`
+	}
+	if err := cur.All(ctx, &dups); err != nil {
+		return err
+	}
+	for _, d := range dups {
+		if _, err := coll.UpdateMany(ctx,
+			bson.M{"_id": bson.M{"$in": d.IDs[1:]}},
+			bson.M{"$set": bson.M{"status": "REJECTED", "reviewedAt": time.Now()}},
+		); err != nil {
+			return err
+		}
+	}
+
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}},
+		Options: options.Index().
+			SetName("userId_pending_unique").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"status": "PENDING"}),
+	})
+	return err
+}
+
+// ensureExpiryIndexes lets Mongo delete short-lived documents once their
+// expiresAt has passed.
+func ensureExpiryIndexes(ctx context.Context, db *mongo.Database) error {
+	for _, coll := range []string{"sessions", "oidc_states", "webauthn_sessions", "mfa_challenges", "login_events", "rate_limits"} {
+		_, err := db.Collection(coll).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetName("expiresAt_ttl").SetExpireAfterSeconds(0),
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", coll, err)
+		}
+	}
+	return nil
+}
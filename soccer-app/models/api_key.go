@@ -0,0 +1,62 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ScopeVotesWrite   = "votes:write"
+	ScopePollsRead    = "polls:read"
+	ScopePollsWrite   = "polls:write"
+	ScopeTeamsWrite   = "teams:write"
+	ScopePlayersRead  = "players:read"
+	ScopePlayersWrite = "players:write"
+)
+
+var validScopes = map[string]bool{
+	ScopeVotesWrite:   true,
+	ScopePollsRead:    true,
+	ScopePollsWrite:   true,
+	ScopeTeamsWrite:   true,
+	ScopePlayersRead:  true,
+	ScopePlayersWrite: true,
+}
+
+func ValidScope(scope string) bool {
+	return validScopes[scope]
+}
+
+// IsWriteScope reports whether scope lets a key change data.
+func IsWriteScope(scope string) bool {
+	return strings.HasSuffix(scope, ":write")
+}
+
+// APIKey lets a bot or integration call the API without a user session.
+// It acts with the permissions of OwnerID, limited to Scopes. Group is set
+// for keys shared by a whole group rather than a single user.
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name       string             `bson:"name" json:"name"`
+	Prefix     string             `bson:"prefix" json:"prefix"` // shown so users can tell keys apart
+	KeyHash    string             `bson:"keyHash" json:"-"`
+	OwnerID    primitive.ObjectID `bson:"ownerId" json:"ownerId"`
+	Group      string             `bson:"group,omitempty" json:"group,omitempty"`
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt  *time.Time         `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+	RevokedAt  *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	LastUsedAt *time.Time         `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+	LastUsedIP string             `bson:"lastUsedIp,omitempty" json:"lastUsedIp,omitempty"`
+}
+
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
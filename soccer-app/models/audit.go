@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditEntry struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Action   string             `bson:"action" json:"action"`
+	ActorID  primitive.ObjectID `bson:"actorId,omitempty" json:"actorId,omitempty"`
+	TargetID primitive.ObjectID `bson:"targetId,omitempty" json:"targetId,omitempty"`
+	IP       string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	Details  string             `bson:"details,omitempty" json:"details,omitempty"`
+	Time     time.Time          `bson:"time" json:"time"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "strings"
+
+// Device is a rough description of the client behind a user agent.
+type Device struct {
+	Browser string `json:"browser"`
+	OS      string `json:"os"`
+	Mobile  bool   `json:"mobile"`
+}
+
+// ParseUserAgent recognises the common browsers and operating systems.
+// Anything else is reported as "Other".
+func ParseUserAgent(ua string) Device {
+	d := Device{Browser: "Other", OS: "Other"}
+
+	// Order matters: Edge and Opera also claim to be Chrome, and Chrome
+	// claims to be Safari
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b.token) {
+			d.Browser = b.name
+			break
+		}
+	}
+
+	for _, o := range []struct{ token, name string }{
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			d.OS = o.name
+			break
+		}
+	}
+
+	d.Mobile = strings.Contains(ua, "Mobile") || d.OS == "iOS" || d.OS == "Android"
+	return d
+}
+
+func (d Device) String() string {
+	return d.Browser + " on " + d.OS
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultGroup is used for users who register without naming a group.
+const DefaultGroup = "default"
+
+type Group struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name"` // unique slug
+	DisplayName string             `bson:"displayName" json:"displayName"`
+	InviteOnly  bool               `bson:"inviteOnly" json:"inviteOnly"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invite lets someone register in an invite-only group. Only the hash of
+// the code is stored.
+type Invite struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CodeHash  string             `bson:"codeHash" json:"-"`
+	Group     string             `bson:"group,omitempty" json:"group,omitempty"`
+	Role      string             `bson:"role,omitempty" json:"role,omitempty"`
+	MaxUses   int                `bson:"maxUses" json:"maxUses"`
+	Uses      int                `bson:"uses" json:"uses"`
+	CreatedBy primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+	RevokedAt *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
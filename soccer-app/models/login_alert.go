@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	AlertImpossibleTravel = "impossible_travel"
+	AlertNewCountry       = "new_country"
+	AlertNewDevice        = "new_device"
+)
+
+// LoginAlert flags a sign-in that doesn't fit the user's history.
+type LoginAlert struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"userId" json:"userId"`
+	Username   string             `bson:"username,omitempty" json:"username,omitempty"`
+	EventID    primitive.ObjectID `bson:"eventId" json:"eventId"`
+	Kind       string             `bson:"kind" json:"kind"`
+	Details    string             `bson:"details" json:"details"`
+	IP         string             `bson:"ip" json:"ip"`
+	Country    string             `bson:"country,omitempty" json:"country,omitempty"`
+	City       string             `bson:"city,omitempty" json:"city,omitempty"`
+	UserAgent  string             `bson:"userAgent" json:"userAgent"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	ResolvedAt *time.Time         `bson:"resolvedAt,omitempty" json:"resolvedAt,omitempty"`
+	ResolvedBy primitive.ObjectID `bson:"resolvedBy,omitempty" json:"resolvedBy,omitempty"`
+}
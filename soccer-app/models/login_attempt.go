@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginAttempt tracks failed logins for a single key, either an account
+// ("account:<username>") or a client IP ("ip:<addr>").
+type LoginAttempt struct {
+	Key         string    `bson:"_id" json:"key"`
+	Failures    int       `bson:"failures" json:"failures"`
+	LastFailure time.Time `bson:"lastFailure" json:"lastFailure"`
+	LockedUntil time.Time `bson:"lockedUntil" json:"lockedUntil"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoginEvent is one request to a sign-in or registration route, kept for
+// the access log until ExpiresAt.
+type LoginEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Time      time.Time          `bson:"time" json:"time"`
+	UserID    primitive.ObjectID `bson:"userId,omitempty" json:"userId,omitempty"`
+	Username  string             `bson:"username,omitempty" json:"username,omitempty"`
+	FirstName string             `bson:"firstName,omitempty" json:"firstName,omitempty"`
+	LastName  string             `bson:"lastName,omitempty" json:"lastName,omitempty"`
+	IP        string             `bson:"ip" json:"ip"`
+	Method    string             `bson:"method" json:"method"`
+	Path      string             `bson:"path" json:"path"`
+	Status    int                `bson:"status" json:"status"`
+	Success   bool               `bson:"success" json:"success"`
+	SignIn    bool               `bson:"signIn,omitempty" json:"signIn,omitempty"` // opened a session
+	UserAgent string             `bson:"userAgent" json:"userAgent"`
+	Referer   string             `bson:"referer,omitempty" json:"referer,omitempty"`
+	RequestID string             `bson:"requestId,omitempty" json:"requestId,omitempty"`
+	Country   string             `bson:"country,omitempty" json:"country,omitempty"`
+	City      string             `bson:"city,omitempty" json:"city,omitempty"`
+	ISP       string             `bson:"isp,omitempty" json:"isp,omitempty"`
+	Lat       string             `bson:"lat,omitempty" json:"lat,omitempty"`
+	Lng       string             `bson:"lng,omitempty" json:"lng,omitempty"`
+	ExpiresAt time.Time          `bson:"expiresAt" json:"-"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MFAChallenge is handed out by a sign-in path that proved the first
+// factor but still needs the user's TOTP or recovery code. Only the hash
+// of the challenge token is stored.
+type MFAChallenge struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	TokenHash string             `bson:"tokenHash"`
+	UserID    primitive.ObjectID `bson:"userId"`
+	ExpiresAt time.Time          `bson:"expiresAt"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OIDCState is the server-side half of an authorization-code round trip.
+// The state value itself is the document ID.
+type OIDCState struct {
+	State        string             `bson:"_id"`
+	Nonce        string             `bson:"nonce"`
+	CodeVerifier string             `bson:"codeVerifier"`
+	LinkUserID   primitive.ObjectID `bson:"linkUserId,omitempty"`
+	ExpiresAt    time.Time          `bson:"expiresAt"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Passkey is a WebAuthn credential registered by a user.
+type Passkey struct {
+	Name       string              `bson:"name" json:"name"`
+	Credential webauthn.Credential `bson:"credential" json:"-"`
+	CreatedAt  time.Time           `bson:"createdAt" json:"createdAt"`
+	LastUsedAt *time.Time          `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+}
+
+// WebAuthnSession holds the challenge for an in-flight registration or
+// login ceremony. Data is the JSON-encoded webauthn.SessionData.
+type WebAuthnSession struct {
+	ID        string             `bson:"_id"`
+	UserID    primitive.ObjectID `bson:"userId,omitempty"`
+	Data      []byte             `bson:"data"`
+	ExpiresAt time.Time          `bson:"expiresAt"`
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PeerRating is one player's assessment of a teammate after a match.
+type PeerRating struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PollID    primitive.ObjectID `bson:"pollId" json:"pollId"`
+	RaterID   primitive.ObjectID `bson:"raterId" json:"raterId"`
+	RateeID   primitive.ObjectID `bson:"rateeId" json:"rateeId"`
+	Skills    []Skill            `bson:"skills" json:"skills"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+const (
+	// MinPeerSamples is how many different raters a skill needs before
+	// peer ratings count at all.
+	MinPeerSamples = 3
+	// peerPrior sets how fast peers take over from self-assessment: with
+	// n ratings the peer mean gets weight n/(n+peerPrior).
+	peerPrior = 3.0
+)
+
+// CalibratedSkill is a skill value blended from self-assessment and peer
+// ratings.
+type CalibratedSkill struct {
+	Name      string  `json:"name"`
+	Self      int     `json:"self,omitempty"`
+	PeerMean  float64 `json:"peerMean,omitempty"`
+	PeerCount int     `json:"peerCount"`
+	Value     float64 `json:"value"`
+}
+
+// Calibrate blends self-reported skills with peer ratings. peers holds, per
+// skill, one value from each distinct rater. Outliers are pulled in to
+// within 1.5 median absolute deviations of the median before averaging.
+// Skills not in the catalogue are dropped.
+func Calibrate(self []Skill, peers map[string][]int, catalogue []SkillDef) []CalibratedSkill {
+	selfValues := make(map[string]int, len(self))
+	for _, s := range self {
+		selfValues[s.Name] = s.Value
+	}
+
+	out := make([]CalibratedSkill, 0, len(catalogue))
+	for _, d := range catalogue {
+		cs := CalibratedSkill{Name: d.Name, PeerCount: len(peers[d.Name])}
+		sv, hasSelf := selfValues[d.Name]
+		if hasSelf {
+			cs.Self = sv
+		}
+
+		if cs.PeerCount > 0 {
+			cs.PeerMean = dampenedMean(peers[d.Name])
+		}
+
+		switch {
+		case cs.PeerCount >= MinPeerSamples && hasSelf:
+			w := float64(cs.PeerCount) / (float64(cs.PeerCount) + peerPrior)
+			cs.Value = w*cs.PeerMean + (1-w)*float64(sv)
+		case cs.PeerCount >= MinPeerSamples:
+			cs.Value = cs.PeerMean
+		case hasSelf:
+			cs.Value = float64(sv)
+		default:
+			continue
+		}
+
+		cs.Value = math.Round(cs.Value*10) / 10
+		cs.PeerMean = math.Round(cs.PeerMean*10) / 10
+		out = append(out, cs)
+	}
+	return out
+}
+
+// CalibratedScore is SkillScore for calibrated values.
+func CalibratedScore(skills []CalibratedSkill, catalogue []SkillDef) float64 {
+	values := make(map[string]float64, len(skills))
+	for _, s := range skills {
+		values[s.Name] = s.Value
+	}
+	return weightedScore(values, catalogue)
+}
+
+func dampenedMean(values []int) float64 {
+	sorted := make([]float64, len(values))
+	for i, v := range values {
+		sorted[i] = float64(v)
+	}
+	sort.Float64s(sorted)
+
+	med := median(sorted)
+	devs := make([]float64, len(sorted))
+	for i, v := range sorted {
+		devs[i] = math.Abs(v - med)
+	}
+	sort.Float64s(devs)
+
+	// With a MAD of 0 (most raters agree) still allow a point either way
+	limit := math.Max(1.5*median(devs), 1)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += math.Min(math.Max(v, med-limit), med+limit)
+	}
+	return sum / float64(len(sorted))
+}
+
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDampenedMean(t *testing.T) {
+	cases := []struct {
+		name   string
+		values []int
+		want   float64
+	}{
+		{"all agree", []int{5, 5, 5}, 5},
+		{"spread within MAD", []int{2, 4, 6, 8}, 5},
+		// MAD is 0, so the outlier is pulled to within one point
+		{"high outlier", []int{5, 5, 5, 10}, 5.25},
+		{"low outlier", []int{1, 9, 9, 9, 9}, 8.8},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := dampenedMean(tc.values); math.Abs(got-tc.want) > 1e-9 {
+				t.Fatalf("dampenedMean(%v) = %v, want %v", tc.values, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	catalogue := []SkillDef{
+		{Name: "speed", Min: 1, Max: 10, Weight: 1},
+		{Name: "passing", Min: 1, Max: 10, Weight: 1},
+	}
+
+	cases := []struct {
+		name  string
+		self  []Skill
+		peers []int
+		want  *CalibratedSkill // nil when the skill is dropped
+	}{
+		{"self only", []Skill{{Name: "speed", Value: 6}}, nil,
+			&CalibratedSkill{Name: "speed", Self: 6, Value: 6}},
+		{"too few raters", []Skill{{Name: "speed", Value: 4}}, []int{10, 10},
+			&CalibratedSkill{Name: "speed", Self: 4, PeerMean: 10, PeerCount: 2, Value: 4}},
+		// n/(n+peerPrior) = 3/6: an even blend
+		{"blend at minimum samples", []Skill{{Name: "speed", Value: 4}}, []int{8, 8, 8},
+			&CalibratedSkill{Name: "speed", Self: 4, PeerMean: 8, PeerCount: 3, Value: 6}},
+		// 6/9 peers, 3/9 self
+		{"peers take over", []Skill{{Name: "speed", Value: 1}}, []int{7, 7, 7, 7, 7, 7},
+			&CalibratedSkill{Name: "speed", Self: 1, PeerMean: 7, PeerCount: 6, Value: 5}},
+		{"peers without self", nil, []int{3, 3, 3},
+			&CalibratedSkill{Name: "speed", PeerMean: 3, PeerCount: 3, Value: 3}},
+		{"nothing known", nil, []int{3}, nil},
+		{"not in catalogue", []Skill{{Name: "juggling", Value: 9}}, nil, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			peers := map[string][]int{"speed": tc.peers}
+			got := Calibrate(tc.self, peers, catalogue)
+
+			if tc.want == nil {
+				if len(got) != 0 {
+					t.Fatalf("got %+v, want nothing", got)
+				}
+				return
+			}
+			if len(got) != 1 || got[0] != *tc.want {
+				t.Fatalf("got %+v, want [%+v]", got, *tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PlayerMergeConflict records a document from the old players collection
+// whose name matched more than one user, so it could not be merged
+// automatically. ID is the player document's ID.
+type PlayerMergeConflict struct {
+	ID         primitive.ObjectID   `bson:"_id" json:"id"`
+	Name       string               `bson:"name" json:"name"`
+	Position   string               `bson:"position" json:"position"`
+	AvgRating  float64              `bson:"avgRating" json:"avgRating"`
+	Candidates []primitive.ObjectID `bson:"candidates" json:"candidates"`
+	ReportedAt time.Time            `bson:"reportedAt" json:"reportedAt"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ResetCode is a single-use code an organiser hands to a user who forgot
+// their secret. Only the hash of the code is stored.
+type ResetCode struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+	CodeHash  string             `bson:"codeHash" json:"-"`
+	IssuedBy  primitive.ObjectID `bson:"issuedBy" json:"issuedBy"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+	UsedAt    *time.Time         `bson:"usedAt,omitempty" json:"usedAt,omitempty"`
+}
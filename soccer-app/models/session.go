@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Session struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"userId" json:"userId"`
+	TokenHash  string             `bson:"tokenHash" json:"-"`
+	MFA        bool               `bson:"mfa" json:"mfa"` // opened with a second factor
+	IP         string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent  string             `bson:"userAgent,omitempty" json:"userAgent,omitempty"`
+	RequestID  string             `bson:"requestId,omitempty" json:"-"` // the sign-in's login event
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	LastSeenAt time.Time          `bson:"lastSeenAt,omitempty" json:"lastSeenAt,omitempty"`
+	ExpiresAt  time.Time          `bson:"expiresAt" json:"expiresAt"`
+}
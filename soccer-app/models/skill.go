@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SkillDef is one entry in a group's skill catalogue. Weight sets how much
+// the skill counts when balancing teams. Retired skills are kept so old
+// skill change requests still make sense, but can no longer be rated.
+type SkillDef struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Group       string             `bson:"group" json:"group"`
+	Name        string             `bson:"name" json:"name"` // unique per group
+	DisplayName string             `bson:"displayName" json:"displayName"`
+	Min         int                `bson:"min" json:"min"`
+	Max         int                `bson:"max" json:"max"`
+	Weight      float64            `bson:"weight" json:"weight"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	RetiredAt   *time.Time         `bson:"retiredAt,omitempty" json:"retiredAt,omitempty"`
+}
+
+// DefaultSkills seeds the catalogue of groups that don't have one yet.
+var DefaultSkills = []SkillDef{
+	{Name: "speed", DisplayName: "Speed", Min: 1, Max: 10, Weight: 1},
+	{Name: "dribbling", DisplayName: "Dribbling", Min: 1, Max: 10, Weight: 1},
+	{Name: "shooting", DisplayName: "Shooting", Min: 1, Max: 10, Weight: 1},
+	{Name: "defending", DisplayName: "Defending", Min: 1, Max: 10, Weight: 1},
+	{Name: "passing", DisplayName: "Passing", Min: 1, Max: 10, Weight: 1},
+	{Name: "stamina", DisplayName: "Stamina", Min: 1, Max: 10, Weight: 1},
+}
+
+// SkillScore is the weighted average of skills scaled to 0-1 by each
+// skill's range. Skills missing from the catalogue are ignored.
+func SkillScore(skills []Skill, catalogue []SkillDef) float64 {
+	values := make(map[string]float64, len(skills))
+	for _, s := range skills {
+		values[s.Name] = float64(s.Value)
+	}
+	return weightedScore(values, catalogue)
+}
+
+func weightedScore(values map[string]float64, catalogue []SkillDef) float64 {
+	var sum, weights float64
+	for _, d := range catalogue {
+		v, ok := values[d.Name]
+		if !ok || d.Weight <= 0 || d.Max <= d.Min {
+			continue
+		}
+		sum += d.Weight * (v - float64(d.Min)) / float64(d.Max-d.Min)
+		weights += d.Weight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return sum / weights
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SkillChange is a self-reported skill update waiting for an organiser.
+type SkillChange struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+	Username    string             `bson:"username" json:"username"`
+	Previous    []Skill            `bson:"previous" json:"previous"`
+	Skills      []Skill            `bson:"skills" json:"skills"`
+	Status      string             `bson:"status" json:"status"` // PENDING | APPROVED | REJECTED
+	RequestedAt time.Time          `bson:"requestedAt" json:"requestedAt"`
+	ReviewedBy  primitive.ObjectID `bson:"reviewedBy,omitempty" json:"reviewedBy,omitempty"`
+	ReviewedAt  *time.Time         `bson:"reviewedAt,omitempty" json:"reviewedAt,omitempty"`
+}
@@ -1,19 +1,154 @@
 package models
 
 import (
+	"regexp"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	RolePlayer    = "player"
+	RoleOrganiser = "organiser"
+	RoleAdmin     = "admin"
+)
+
+// roleRank orders roles so that a higher role includes the permissions of
+// the ones below it.
+var roleRank = map[string]int{
+	RolePlayer:    1,
+	RoleOrganiser: 2,
+	RoleAdmin:     3,
+}
+
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// HasRole reports whether a user with role satisfies the required role.
+// An empty role is treated as player.
+func HasRole(role, required string) bool {
+	if role == "" {
+		role = RolePlayer
+	}
+	return roleRank[role] >= roleRank[required]
+}
+
+// Outranks reports whether role is strictly higher than other. Empty
+// roles are treated as player.
+func Outranks(role, other string) bool {
+	if other == "" {
+		other = RolePlayer
+	}
+	return HasRole(role, other) && !HasRole(other, role)
+}
+
 type User struct {
 	UserID     primitive.ObjectID `bson:"_id,omitempty" json:"userId"`
+	Username   string             `bson:"username" json:"username"`
+	Email      string             `bson:"email,omitempty" json:"email,omitempty"`
 	FirstName  string             `bson:"firstName" json:"firstName"`
 	LastName   string             `bson:"lastName" json:"lastName"`
+	Group      string             `bson:"group,omitempty" json:"group,omitempty"`
 	Position   string             `bson:"position" json:"position"`
 	Skills     []Skill            `bson:"skills" json:"skills"`
-	SecretHash string             `bson:"secretHash"`
-	CreatedAt  time.Time          `bson:"createdAt"`
+	AvgRating  float64            `bson:"avgRating" json:"avgRating"`
+	Role       string             `bson:"role" json:"role"`
+	SecretHash string             `bson:"secretHash" json:"-"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+
+	// TOTP two-factor authentication
+	TOTPEnabled   bool     `bson:"totpEnabled" json:"totpEnabled"`
+	TOTPSecret    string   `bson:"totpSecret,omitempty" json:"-"`
+	TOTPLastStep  int64    `bson:"totpLastStep,omitempty" json:"-"`
+	RecoveryCodes []string `bson:"recoveryCodes,omitempty" json:"-"` // hashed
+
+	// WebAuthn credentials for passwordless login
+	Passkeys []Passkey `bson:"passkeys,omitempty" json:"-"`
+
+	// External OIDC accounts linked to this user
+	Identities []Identity `bson:"identities,omitempty" json:"identities,omitempty"`
+
+	// Set when a suspicious sign-in locks password login until a reset
+	// code is redeemed
+	ReverifyRequired bool `bson:"reverifyRequired,omitempty" json:"reverifyRequired,omitempty"`
+
+	// Sign-ins before this (the latest hold or reset) are left out when
+	// looking for suspicious ones, since they may not have been the user
+	LoginHistoryFrom time.Time `bson:"loginHistoryFrom,omitempty" json:"-"`
+
+	// Set by the handle migration when another user has the same name
+	PossibleDuplicate bool `bson:"possibleDuplicate,omitempty" json:"possibleDuplicate,omitempty"`
+
+	// Placeholder ID the user's votes, teams and ratings are moved to
+	// while the account is being deleted; set until the deletion finishes
+	DeletingAs primitive.ObjectID `bson:"deletingAs,omitempty" json:"-"`
+}
+
+// FullName joins first and last name; players merged from the old players
+// collection may only have a first name.
+func (u User) FullName() string {
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
+// HasCredentials reports whether someone can sign in as u, as opposed to
+// a player record an organiser keeps on their behalf.
+func (u User) HasCredentials() bool {
+	return u.SecretHash != "" || len(u.Passkeys) > 0 || len(u.Identities) > 0
+}
+
+// SplitName splits a single display name into first and last name.
+func SplitName(name string) (firstName, lastName string) {
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	return fields[0], strings.Join(fields[1:], " ")
+}
+
+var handlePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{2,29}$`)
+
+// NormalizeHandle lower-cases and trims a username for storage and lookup.
+func NormalizeHandle(h string) string {
+	return strings.ToLower(strings.TrimSpace(h))
+}
+
+// ValidHandle reports whether h (already normalized) is an acceptable
+// username: 3-30 characters of a-z, 0-9, '.', '_' or '-'.
+func ValidHandle(h string) bool {
+	return handlePattern.MatchString(h)
+}
+
+// HandleFromName derives a handle such as "alex.smith" from a user's name.
+// The result may still collide with an existing handle.
+func HandleFromName(firstName, lastName string) string {
+	clean := func(s string) string {
+		var b strings.Builder
+		for _, r := range strings.ToLower(s) {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+				b.WriteRune(r)
+			}
+		}
+		return b.String()
+	}
+
+	h := strings.Trim(clean(firstName)+"."+clean(lastName), ".")
+	for len(h) < 3 {
+		h += "0"
+	}
+	if len(h) > 26 {
+		h = h[:26]
+	}
+	return h
+}
+
+type Identity struct {
+	Issuer   string    `bson:"issuer" json:"issuer"`
+	Subject  string    `bson:"subject" json:"subject"`
+	Email    string    `bson:"email,omitempty" json:"email,omitempty"`
+	LinkedAt time.Time `bson:"linkedAt" json:"linkedAt"`
 }
 
 type Skill struct {
@@ -9,6 +9,8 @@ import (
 type Vote struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	PollID    primitive.ObjectID `bson:"pollId" json:"pollId"`
+	UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+	Username  string             `bson:"username" json:"username"`
 	FirstName string             `bson:"firstName" json:"firstName"`
 	LastName  string             `bson:"lastName" json:"lastName"`
 	Secret    string             `bson:"secret" json:"secret"`
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"soccer-app/config"
+)
+
+// Memory keeps buckets in this process. Buckets that have refilled
+// completely are indistinguishable from new ones, so a background sweep
+// drops them to keep the map from growing with every client ever seen.
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	stop    chan struct{}
+	once    sync.Once
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when the bucket will have refilled completely
+}
+
+// NewMemory starts a store that sweeps idle buckets every interval.
+func NewMemory(interval time.Duration) *Memory {
+	m := &Memory{
+		buckets: make(map[string]*bucket),
+		stop:    make(chan struct{}),
+	}
+	go m.sweep(interval)
+	return m
+}
+
+func (m *Memory) Take(_ context.Context, key string, limit config.Limit, now time.Time) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), last: now}
+		m.buckets[key] = b
+	}
+
+	var res Result
+	b.tokens, res = refill(b.tokens, b.last, now, limit)
+	b.last = now
+	b.full = now.Add(res.Reset)
+	return res, nil
+}
+
+// Len reports how many buckets are held.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buckets)
+}
+
+// Close stops the sweep.
+func (m *Memory) Close() error {
+	m.once.Do(func() { close(m.stop) })
+	return nil
+}
+
+func (m *Memory) sweep(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-t.C:
+			m.evict(now)
+		}
+	}
+}
+
+func (m *Memory) evict(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"soccer-app/config"
+	"soccer-app/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	rejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimit_rejections_total",
+		Help: "Requests rejected for being over a rate limit, by policy.",
+	}, []string{"policy"})
+
+	storeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimit_store_errors_total",
+		Help: "Rate limit store failures, after which the request was allowed.",
+	}, []string{"policy"})
+)
+
+// Headers set on limited responses, for CORS to expose.
+var Headers = []string{"RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"}
+
+// Policy is a named limit and the client a request counts against.
+// Policies with the same name share buckets.
+type Policy struct {
+	Name  string
+	Limit config.Limit
+	Key   func(c *gin.Context) string
+}
+
+// ByIP counts requests against the client's IP.
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByUser counts signed-in users' requests against their account and
+// everyone else's against their IP. It must run after the auth middleware.
+func ByUser(c *gin.Context) string {
+	if userID := c.GetString("userId"); userID != "" {
+		return "user:" + userID
+	}
+	return ByIP(c)
+}
+
+// Middleware rejects requests over p's limit with 429 and reports the
+// client's quota in RateLimit-* headers. If the store fails the request is
+// let through: an outage shouldn't take the API down with it.
+func Middleware(store Store, p Policy) gin.HandlerFunc {
+	policy := fmt.Sprintf("%d;w=%d", p.Limit.Requests, int(p.Limit.Window.Seconds()))
+
+	return func(c *gin.Context) {
+		key := p.Name + "|" + p.Key(c)
+		res, err := store.Take(c.Request.Context(), key, p.Limit, time.Now())
+		if err != nil {
+			storeErrors.WithLabelValues(p.Name).Inc()
+			logging.From(c).Error("rate limit store failed, allowing request", "policy", p.Name, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(p.Limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+		if !res.Allowed {
+			rejections.WithLabelValues(p.Name).Inc()
+			c.Header("Retry-After", strconv.Itoa(max(1, ceilSeconds(res.RetryAfter))))
+			logging.From(c).Warn("rate limited", "policy", p.Name, "key", key)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
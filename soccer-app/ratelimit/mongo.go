@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"soccer-app/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Mongo keeps buckets in the rate_limits collection so every instance
+// behind a load balancer shares them. Each Take is a single atomic
+// update; a TTL index on expiresAt removes buckets once they are full.
+type Mongo struct {
+	coll *mongo.Collection
+}
+
+func NewMongo(db *mongo.Database) *Mongo {
+	return &Mongo{coll: db.Collection("rate_limits")}
+}
+
+func (m *Mongo) Take(ctx context.Context, key string, limit config.Limit, now time.Time) (Result, error) {
+	capacity := float64(limit.Requests)
+	perMs := capacity / float64(limit.Window.Milliseconds())
+
+	// Same arithmetic as refill, done server-side so concurrent requests
+	// from different instances can't both spend the last token
+	elapsedMs := bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$last", now}}}}}}
+	tokens := bson.M{"$min": bson.A{capacity, bson.M{"$add": bson.A{
+		bson.M{"$ifNull": bson.A{"$tokens", capacity}},
+		bson.M{"$multiply": bson.A{elapsedMs, perMs}},
+	}}}}
+	hasToken := bson.M{"$gte": bson.A{"$tokens", 1}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tokens": tokens, "last": now}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed": hasToken,
+			"tokens":  bson.M{"$cond": bson.A{hasToken, bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"expiresAt": bson.M{"$add": bson.A{now, bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{capacity, "$tokens"}}, perMs}}}},
+		}}},
+	}
+
+	var doc struct {
+		Tokens  float64 `bson:"tokens"`
+		Allowed bool    `bson:"allowed"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := m.coll.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&doc)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another instance created the bucket first; ours now updates it
+		err = m.coll.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&doc)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	res := Result{
+		Allowed:   doc.Allowed,
+		Remaining: int(doc.Tokens),
+		Reset:     seconds((capacity - doc.Tokens) / perMs / 1000),
+	}
+	if !doc.Allowed {
+		res.RetryAfter = seconds((1 - doc.Tokens) / perMs / 1000)
+	}
+	return res, nil
+}
@@ -0,0 +1,61 @@
+// Package ratelimit limits clients with token buckets kept in a pluggable
+// store, and provides the gin middleware that applies a policy per route.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"soccer-app/config"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed    bool
+	Remaining  int           // whole tokens left after this request
+	RetryAfter time.Duration // until the next token, when not allowed
+	Reset      time.Duration // until the bucket is full again
+}
+
+// Store keeps buckets by key. Take must be safe for concurrent use and,
+// for stores shared between instances, atomic across them.
+type Store interface {
+	Take(ctx context.Context, key string, limit config.Limit, now time.Time) (Result, error)
+}
+
+// refill tops up a bucket holding tokens at last to now and takes one
+// token if there is one. It returns the tokens left and the result.
+func refill(tokens float64, last, now time.Time, limit config.Limit) (float64, Result) {
+	capacity := float64(limit.Requests)
+	perSecond := capacity / limit.Window.Seconds()
+
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed*perSecond)
+	}
+
+	var res Result
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / perSecond)
+	}
+	res.Remaining = int(tokens)
+	res.Reset = seconds((capacity - tokens) / perSecond)
+	return tokens, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// New builds the store named in cfg. db is only used by the mongo store.
+func New(cfg config.RateLimit, db *mongo.Database) Store {
+	if cfg.Store == "mongo" {
+		return NewMongo(db)
+	}
+	return NewMemory(time.Minute)
+}
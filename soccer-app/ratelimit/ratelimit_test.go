@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"soccer-app/config"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// Three requests per three seconds: one token back every second.
+var limit = config.Limit{Requests: 3, Window: 3 * time.Second}
+
+// Whole seconds, so the Mongo store's millisecond dates lose nothing.
+var t0 = time.Unix(1_700_000_000, 0)
+
+func TestRefill(t *testing.T) {
+	tests := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		left    float64
+		want    Result
+	}{
+		{"full", 3, 0, 2, Result{Allowed: true, Remaining: 2, Reset: time.Second}},
+		{"last token", 1, 0, 0, Result{Allowed: true, Remaining: 0, Reset: 3 * time.Second}},
+		{"empty", 0, 0, 0, Result{RetryAfter: time.Second, Reset: 3 * time.Second}},
+		{"half a token back", 0, 500 * time.Millisecond, 0.5, Result{RetryAfter: 500 * time.Millisecond, Reset: 2500 * time.Millisecond}},
+		{"a token back", 0, time.Second, 0, Result{Allowed: true, Remaining: 0, Reset: 3 * time.Second}},
+		{"capped at capacity", 1, time.Hour, 2, Result{Allowed: true, Remaining: 2, Reset: time.Second}},
+		{"clock went backwards", 1, -time.Second, 0, Result{Allowed: true, Remaining: 0, Reset: 3 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			left, got := refill(tt.tokens, t0, t0.Add(tt.elapsed), limit)
+			if left != tt.left || got != tt.want {
+				t.Fatalf("refill = %v, %+v; want %v, %+v", left, got, tt.left, tt.want)
+			}
+		})
+	}
+}
+
+// testStore runs the same requests through a store and checks each result.
+func testStore(t *testing.T, s Store) {
+	steps := []struct {
+		at   time.Duration
+		want Result
+	}{
+		{0, Result{Allowed: true, Remaining: 2, Reset: time.Second}},
+		{0, Result{Allowed: true, Remaining: 1, Reset: 2 * time.Second}},
+		{0, Result{Allowed: true, Remaining: 0, Reset: 3 * time.Second}},
+		{0, Result{RetryAfter: time.Second, Reset: 3 * time.Second}},
+		{500 * time.Millisecond, Result{RetryAfter: 500 * time.Millisecond, Reset: 2500 * time.Millisecond}},
+		{1100 * time.Millisecond, Result{Allowed: true, Remaining: 0, Reset: 2900 * time.Millisecond}},
+		{time.Minute, Result{Allowed: true, Remaining: 2, Reset: time.Second}},
+	}
+	ctx := context.Background()
+	for i, st := range steps {
+		got, err := s.Take(ctx, "ip:192.0.2.1", limit, t0.Add(st.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The Mongo store works in floating-point milliseconds
+		got.RetryAfter = got.RetryAfter.Round(time.Millisecond)
+		got.Reset = got.Reset.Round(time.Millisecond)
+		if got != st.want {
+			t.Fatalf("request %d at +%v: got %+v, want %+v", i+1, st.at, got, st.want)
+		}
+	}
+
+	// Other keys have buckets of their own
+	if got, err := s.Take(ctx, "ip:192.0.2.2", limit, t0); err != nil || !got.Allowed {
+		t.Fatalf("other client: %+v, %v", got, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	m := NewMemory(time.Hour)
+	defer m.Close()
+	testStore(t, m)
+}
+
+func TestMongoStore(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := client.Database("soccer_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		_ = db.Drop(context.Background())
+		_ = client.Disconnect(context.Background())
+	})
+
+	testStore(t, NewMongo(db))
+}
+
+func TestMemoryEvictsFullBuckets(t *testing.T) {
+	m := NewMemory(time.Hour)
+	defer m.Close()
+	ctx := context.Background()
+
+	m.Take(ctx, "a", limit, t0) // full again after a second
+	m.Take(ctx, "b", limit, t0)
+	m.Take(ctx, "b", limit, t0) // after two
+
+	m.evict(t0.Add(1500 * time.Millisecond))
+	if m.Len() != 1 {
+		t.Fatalf("%d buckets after the first refilled, want 1", m.Len())
+	}
+	m.evict(t0.Add(2 * time.Second))
+	if m.Len() != 0 {
+		t.Fatalf("%d buckets after both refilled, want 0", m.Len())
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	m := NewMemory(time.Hour)
+	defer m.Close()
+
+	r := gin.New()
+	r.GET("/", Middleware(m, Policy{Name: "test", Limit: config.Limit{Requests: 1, Window: time.Hour}, Key: ByIP}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w
+	}
+
+	w := get()
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("RateLimit-Policy") != "1;w=3600" {
+		t.Fatalf("first request: %d %v", w.Code, w.Header())
+	}
+	w = get()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: status = %d, want 429", w.Code)
+	}
+	// One token an hour: the next is about an hour away
+	if ra := w.Header().Get("Retry-After"); ra != "3600" && ra != "3599" {
+		t.Fatalf("Retry-After = %q, want about an hour", ra)
+	}
+}